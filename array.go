@@ -0,0 +1,59 @@
+package bom
+
+import (
+	"strings"
+)
+
+// WhereArrayElem adds a condition on a field of the elements of the array at path.
+// All the where conditions under a path declared this way, including plain WhereEq("items.sku", ...),
+// are combined into one $elemMatch so they must hold for the SAME element. Separate conditions on
+// "items.sku" and "items.status" without it match when ANY element has the sku and ANY, possibly
+// another, element has the status.
+func (b *Bom) WhereArrayElem(path string, field string, conditions string, value interface{}) *Bom {
+	b.AsArray(path)
+	return b.WhereConditions(path+"."+field, conditions, value)
+}
+
+// AsArray declares path as an array of embedded documents, see WhereArrayElem
+func (b *Bom) AsArray(path string) *Bom {
+	for _, p := range b.arrayPaths {
+		if p == path {
+			return b
+		}
+	}
+	b.arrayPaths = append(b.arrayPaths, path)
+	return b
+}
+
+func (b *Bom) arrayPath(field string) (path string, rest string, ok bool) {
+	for _, p := range b.arrayPaths {
+		if strings.HasPrefix(field, p+".") {
+			return p, strings.TrimPrefix(field, p+"."), true
+		}
+	}
+	return "", "", false
+}
+
+// checkArrayPaths warns once about undeclared dot-paths sharing a prefix, which match across
+// different elements when the prefix is an array
+func (b *Bom) checkArrayPaths() {
+	if b.arrayPathsWarned || b.logger == nil {
+		return
+	}
+	prefixes := make(map[string]int)
+	for _, cnd := range b.whereConditions {
+		field := cnd["field"].(string)
+		if _, _, ok := b.arrayPath(field); ok {
+			continue
+		}
+		if i := strings.LastIndex(field, "."); i > 0 {
+			prefix := field[:i]
+			prefixes[prefix]++
+			if prefixes[prefix] == 2 {
+				b.arrayPathsWarned = true
+				b.warnf("bom: several conditions on %s.* may match different elements if %s is an array, use WhereArrayElem or AsArray", prefix, prefix)
+				return
+			}
+		}
+	}
+}
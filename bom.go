@@ -4,7 +4,9 @@ package bom
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
@@ -12,9 +14,12 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 type (
@@ -23,6 +28,8 @@ type (
 		dbName                  string
 		dbCollection            string
 		queryTimeout            time.Duration
+		readQueryTimeout        time.Duration
+		writeQueryTimeout       time.Duration
 		condition               interface{}
 		skipWhenUpdating        map[string]bool
 		whereConditions         []map[string]interface{}
@@ -30,6 +37,9 @@ type (
 		inConditions            []map[string]interface{}
 		notInConditions         []map[string]interface{}
 		notConditions           []map[string]interface{}
+		norConditions           []map[string]interface{}
+		rangeConditions         []map[string]interface{}
+		encryptedConditions     []map[string]interface{}
 		aggregateOptions        []*options.AggregateOptions
 		updateOptions           []*options.UpdateOptions
 		insertOptions           []*options.InsertOneOptions
@@ -41,12 +51,54 @@ type (
 		lastId                  string
 		useAggrigate            bool
 		selectArg               []interface{}
+		maxResults              int
+		truncated               bool
+		stages                  []primitive.D
+		startAtOperationTime    *primitive.Timestamp
+		geoNear                 primitive.D
+		hexObjectIDs            bool
+		readPreference          *readpref.ReadPref
+		withStats               bool
+		collation               *options.Collation
+		hint                    interface{}
+		bypassValidation        bool
+		registry                *bsoncodec.Registry
+		cacheTTL                time.Duration
+		cacheKey                string
+		writeConcern            *writeconcern.WriteConcern
+		wTimeout                time.Duration
+		inChunks                *inChunks
+		logger                  Logger
+		shardKey                []string
+		shardKeyWarned          bool
+		arrayPaths              []string
+		arrayPathsWarned        bool
+		maxScan                 int64
+		projection              interface{}
+		defaultProjection       interface{}
+		partialResults          bool
+		pendingUpdate           *Update
+		requestIDFunc           func(ctx context.Context) string
+		err                     error
+		upsert                  bool
+		noAutoMatch             bool
+		batchSize               int32
+		maxAwaitTime            time.Duration
+		allowedFields           map[string]bool
+		createdField            string
 	}
 	Pagination struct {
-		TotalCount  int32
-		TotalPages  int32
-		CurrentPage int32
-		Size        int32
+		TotalCount  int32            `json:"total_count" bson:"total_count"`
+		TotalPages  int32            `json:"total_pages" bson:"total_pages"`
+		CurrentPage int32            `json:"current_page" bson:"current_page"`
+		Size        int32            `json:"size" bson:"size"`
+		HasNext     bool             `json:"has_next" bson:"has_next"`
+		HasPrevious bool             `json:"has_previous" bson:"has_previous"`
+		Stats       *PaginationStats `json:"stats,omitempty" bson:"stats,omitempty"`
+	}
+	PaginationStats struct {
+		CountDuration time.Duration `json:"count_duration" bson:"count_duration"`
+		FindDuration  time.Duration `json:"find_duration" bson:"find_duration"`
 	}
 	Sort struct {
 		Field string
@@ -56,20 +108,46 @@ type (
 		Page int32
 		Size int32
 	}
-	Size      int32
-	Option    func(*Bom) error
+	Size   int32
+	Option func(*Bom) error
+	// Logger receives the builder's warnings, *log.Logger satisfies it
+	Logger interface {
+		Printf(format string, v ...interface{})
+	}
 	ElemMatch struct {
 		Key string
 		Val interface{}
 	}
+	computedField struct {
+		as   string
+		expr interface{}
+	}
+	excludedField string
+	GeoJSON       struct {
+		Type        string    `json:"type" bson:"type"`
+		Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+	}
 )
 
 const (
 	DefaultQueryTimeout = 5 * time.Second
 	DefaultSize         = 20
+	// MaxSize is the largest page size NewLimit lets through
+	MaxSize = 1000
+	// MinMaxStaleness is the smallest max staleness the driver accepts
+	MinMaxStaleness = 90 * time.Second
 )
 
 var (
+	// ErrNotFound is the error of a SingleResult whose query matched nothing, it is mongo.ErrNoDocuments
+	ErrNotFound = mongo.ErrNoDocuments
+	// ErrMultipleFound is returned by FindExactlyOne when more than one document matches
+	ErrMultipleFound = errors.New("more than one document matches")
+	// ErrMixedProjection is returned when Select and Exclude are combined, _id aside MongoDB forbids mixing them
+	ErrMixedProjection = errors.New("projection can't mix included and excluded fields")
+	// ErrInvalidOption wraps the errors of options given an invalid value, New returns them
+	ErrInvalidOption = errors.New("invalid option")
+
 	mType            = map[string]int32{"asc": 1, "desc": -1}
 	skipWhenUpdating = map[string]bool{"id": true, "createdat": true, "updatedat": true}
 )
@@ -82,7 +160,7 @@ func New(options ...Option) (*Bom, error) {
 			CurrentPage: 1,
 		},
 		skipWhenUpdating: skipWhenUpdating,
-		limit:            &Limit{Page: 1, Size: DefaultSize},
+		limit:            DefaultLimit(),
 	}
 	for _, option := range options {
 		if err := option(b); err != nil {
@@ -99,6 +177,11 @@ func ElMatch(key string, val interface{}) ElemMatch {
 	return ElemMatch{Key: key, Val: val}
 }
 
+// Point builds a GeoJSON point, note that GeoJSON puts longitude first
+func Point(lng, lat float64) GeoJSON {
+	return GeoJSON{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
 func ToObj(id string) primitive.ObjectID {
 	objectID, _ := primitive.ObjectIDFromHex(id)
 	return objectID
@@ -113,6 +196,70 @@ func ToObjects(ids []string) []primitive.ObjectID {
 	return objectIds
 }
 
+// ToObjE is ToObj returning the parse error instead of a zero ObjectID
+func ToObjE(id string) (primitive.ObjectID, error) {
+	return primitive.ObjectIDFromHex(id)
+}
+
+// ToObjectsE is ToObjects failing on the first invalid id
+func ToObjectsE(ids []string) ([]primitive.ObjectID, error) {
+	objectIds := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object id %q: %w", id, err)
+		}
+		objectIds = append(objectIds, objectId)
+	}
+	return objectIds, nil
+}
+
+// HexID returns the _id of a decoded document as a hex string, whether it is still an ObjectID or already converted
+func HexID(doc bson.M) string {
+	switch id := doc["_id"].(type) {
+	case primitive.ObjectID:
+		return id.Hex()
+	case string:
+		return id
+	}
+	return ""
+}
+
+// HexObjectIDs walks a decoded value and replaces every ObjectID, including nested documents and arrays, with its hex string
+func HexObjectIDs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.M:
+		for key, val := range v {
+			v[key] = HexObjectIDs(val)
+		}
+		return v
+	case primitive.D:
+		for i := range v {
+			v[i].Value = HexObjectIDs(v[i].Value)
+		}
+		return v
+	case primitive.A:
+		for i := range v {
+			v[i] = HexObjectIDs(v[i])
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = HexObjectIDs(v[i])
+		}
+		return v
+	case []primitive.ObjectID:
+		hex := make([]string, 0, len(v))
+		for _, id := range v {
+			hex = append(hex, id.Hex())
+		}
+		return hex
+	}
+	return value
+}
+
 func SetMongoClient(client *mongo.Client) Option {
 	return func(b *Bom) error {
 		b.client = client
@@ -122,6 +269,9 @@ func SetMongoClient(client *mongo.Client) Option {
 
 func SetDatabaseName(dbName string) Option {
 	return func(b *Bom) error {
+		if dbName == "" {
+			return fmt.Errorf("%w: database name is empty", ErrInvalidOption)
+		}
 		b.dbName = dbName
 		return nil
 	}
@@ -136,18 +286,87 @@ func SetSkipWhenUpdating(fieldsMap map[string]bool) Option {
 
 func SetCollection(collection string) Option {
 	return func(b *Bom) error {
+		if collection == "" {
+			return fmt.Errorf("%w: collection name is empty", ErrInvalidOption)
+		}
 		b.dbCollection = collection
 		return nil
 	}
 }
 
+// SetHexObjectIDs makes every bson.M returned by the builder carry ObjectIDs as hex strings
+func SetHexObjectIDs(enabled bool) Option {
+	return func(b *Bom) error {
+		b.hexObjectIDs = enabled
+		return nil
+	}
+}
+
+// SetRegistry sets the BSON codec registry of the collection, register custom encoders and decoders
+// (e.g. a decimal type to primitive.Decimal128) so they round-trip through writes and decodes
+func SetRegistry(registry *bsoncodec.Registry) Option {
+	return func(b *Bom) error {
+		b.registry = registry
+		return nil
+	}
+}
+
+func SetLogger(logger Logger) Option {
+	return func(b *Bom) error {
+		b.logger = logger
+		return nil
+	}
+}
+
+// SetDefaultProjection applies projection to every find, find-and-modify and aggregation of the builder,
+// e.g. {"passwordHash": 0} so sensitive fields never leave the database by accident.
+// Select and an inclusion WithProjection replace it for a query, Exclude and an exclusion WithProjection
+// hide their fields on top of it.
+func SetDefaultProjection(projection interface{}) Option {
+	return func(b *Bom) error {
+		b.defaultProjection = projection
+		return nil
+	}
+}
+
+// SetCreatedField makes CreatedWithin compare the date field name instead of the _id timestamp
+func SetCreatedField(name string) Option {
+	return func(b *Bom) error {
+		b.createdField = name
+		return nil
+	}
+}
+
+// SetRequestIDFunc comments every find with the id fn returns for the context of the call, the comment shows up
+// in the profiler and currentOp so a slow query can be traced back to its request. An empty id adds no comment.
+// Only the WithContext methods carry the caller's context, the others pass a background one.
+func SetRequestIDFunc(fn func(ctx context.Context) string) Option {
+	return func(b *Bom) error {
+		b.requestIDFunc = fn
+		return nil
+	}
+}
+
 func SetQueryTimeout(time time.Duration) Option {
 	return func(b *Bom) error {
+		if time <= 0 {
+			return fmt.Errorf("%w: query timeout must be positive, got %s", ErrInvalidOption, time)
+		}
 		b.queryTimeout = time
 		return nil
 	}
 }
 
+// SetTimeouts sets separate deadlines for reads (find, count, aggregate) and writes (insert, update, delete),
+// a zero value falls back to the query timeout
+func SetTimeouts(read, write time.Duration) Option {
+	return func(b *Bom) error {
+		b.readQueryTimeout = read
+		b.writeQueryTimeout = write
+		return nil
+	}
+}
+
 func (b *Bom) WithDB(dbName string) *Bom {
 	b.dbName = dbName
 	return b
@@ -163,18 +382,90 @@ func (b *Bom) WithTimeout(time time.Duration) *Bom {
 	return b
 }
 
+// WithSecondaryPreferred routes reads (List, FindOne, counts, aggregations) to secondaries
+// and falls back to the primary when none is available, writes always go to the primary
+func (b *Bom) WithSecondaryPreferred() *Bom {
+	b.readPreference = readpref.SecondaryPreferred()
+	return b
+}
+
+func (b *Bom) WithWriteConcern(writeConcern *writeconcern.WriteConcern) *Bom {
+	b.writeConcern = writeConcern
+	return b
+}
+
+// WithWTimeout makes a write fail once the write concern can't be acknowledged within d,
+// e.g. a w:majority write on a partitioned replica set, instead of waiting for the context deadline.
+// It is added to the concern given to WithWriteConcern, in any call order, or to the server default one.
+func (b *Bom) WithWTimeout(d time.Duration) *Bom {
+	b.wTimeout = d
+	return b
+}
+
+// WithReadPreference sets the read preference of the read methods, writes always go to the primary
+func (b *Bom) WithReadPreference(readPreference *readpref.ReadPref) *Bom {
+	b.readPreference = readPreference
+	return b
+}
+
+// WithMaxStaleness keeps reads away from secondaries lagging more than d behind the primary.
+// It keeps the mode set by WithReadPreference or WithSecondaryPreferred (secondaryPreferred by default, tag sets are dropped)
+// and fails for d below MinMaxStaleness.
+func (b *Bom) WithMaxStaleness(d time.Duration) (*Bom, error) {
+	if d < MinMaxStaleness {
+		return b, fmt.Errorf("max staleness %s is below the minimum of %s", d, MinMaxStaleness)
+	}
+	mode := readpref.SecondaryPreferredMode
+	if b.readPreference != nil && b.readPreference.Mode() != readpref.PrimaryMode {
+		mode = b.readPreference.Mode()
+	}
+	readPreference, err := readpref.New(mode, readpref.WithMaxStaleness(d))
+	if err != nil {
+		return b, err
+	}
+	b.readPreference = readPreference
+	return b, nil
+}
+
+// WithCondition sets a raw filter document, conditions added with the fluent methods are ANDed with it
 func (b *Bom) WithCondition(condition interface{}) *Bom {
 	b.condition = condition
 	return b
 }
 
+// NewLimit builds a valid Limit: a page below 1 becomes 1, a size below 1 becomes DefaultSize
+// and a size above MaxSize becomes MaxSize
+func NewLimit(page, size int32) *Limit {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = DefaultSize
+	}
+	if size > MaxSize {
+		size = MaxSize
+	}
+	return &Limit{Page: page, Size: size}
+}
+
+// DefaultLimit is the first page of DefaultSize
+func DefaultLimit() *Limit {
+	return NewLimit(1, DefaultSize)
+}
+
+// WithLimit sets the page and size through NewLimit, a page or size below 1 keeps the current one
 func (b *Bom) WithLimit(limit *Limit) *Bom {
-	if limit.Page > 0 {
-		b.limit.Page = limit.Page
+	if limit == nil {
+		return b
+	}
+	page, size := limit.Page, limit.Size
+	if page < 1 {
+		page = b.limit.Page
 	}
-	if limit.Size > 0 {
-		b.limit.Size = limit.Size
+	if size < 1 {
+		size = b.limit.Size
 	}
+	b.limit = NewLimit(page, size)
 	return b
 }
 
@@ -183,14 +474,87 @@ func (b *Bom) WithLastId(lastId string) *Bom {
 	return b
 }
 
+// WithSort adds a sort by sort.Field, which is used as given, each further sort only breaks the ties of the previous ones.
+// An array field sorts by its smallest element ascending and by its largest descending,
+// SortByArrayMin and SortByArrayMax spell that out.
 func (b *Bom) WithSort(sort *Sort) *Bom {
 	b.sort = append(b.sort, sort)
 	return b
 }
 
+// AddSort adds a sort by field in order ("asc" or "desc"), AddSort("lastName", "asc").AddSort("firstName", "asc")
+// sorts by lastName and then by firstName
+func (b *Bom) AddSort(field, order string) *Bom {
+	return b.WithSort(&Sort{Field: field, Type: order})
+}
+
+// SortByArrayMin sorts ascending by the smallest element of the array field, documents with an empty array come first
+func (b *Bom) SortByArrayMin(field string) *Bom {
+	return b.WithSort(&Sort{Field: field, Type: "asc"})
+}
+
+// SortByArrayMax sorts descending by the largest element of the array field
+func (b *Bom) SortByArrayMax(field string) *Bom {
+	return b.WithSort(&Sort{Field: field, Type: "desc"})
+}
+
+// WithMaxResults caps the number of documents List and ListWithPagination hand to the callback,
+// use Truncated to find out whether the cap was hit
+func (b *Bom) WithMaxResults(n int) *Bom {
+	if n > 0 {
+		b.maxResults = n
+	}
+	return b
+}
+
+// Truncated reports whether the last List or ListWithPagination stopped early because of WithMaxResults
+func (b *Bom) Truncated() bool {
+	return b.truncated
+}
+
+// WithStats makes ListWithPagination report how long the count and the find took in Pagination.Stats,
+// the find duration covers the whole cursor iteration including the callbacks
+func (b *Bom) WithStats() *Bom {
+	b.withStats = true
+	return b
+}
+
+// WithBatchSize sets how many documents each round-trip of finds and aggregations returns
+func (b *Bom) WithBatchSize(n int32) *Bom {
+	b.batchSize = n
+	return b
+}
+
+// WithMaxAwaitTime sets how long the server waits for new results on a getMore of a tailable aggregation cursor
+func (b *Bom) WithMaxAwaitTime(d time.Duration) *Bom {
+	b.maxAwaitTime = d
+	return b
+}
+
+// WithCollation sets the collation of every read and write of the builder, finds, counts, aggregations,
+// updates, replaces and deletes alike, so they all match the same documents
+func (b *Bom) WithCollation(collation *options.Collation) *Bom {
+	b.collation = collation
+	return b
+}
+
+// WithHint forces the index used by finds, counts and aggregations, either by name or by key document.
+// MongoDB accepts a single index only, see SuggestIndex for queries that need several
+func (b *Bom) WithHint(hint interface{}) *Bom {
+	b.hint = hint
+	return b
+}
+
+// WithBypassValidation lets inserts, updates and bulk writes skip the collection's schema validator,
+// the user needs the bypassDocumentValidation privilege
+func (b *Bom) WithBypassValidation() *Bom {
+	b.bypassValidation = true
+	return b
+}
+
 func (b *Bom) WithSize(size int32) *Bom {
 	if size > 0 {
-		b.limit.Size = size
+		b.limit = NewLimit(b.limit.Page, size)
 	}
 	return b
 }
@@ -230,18 +594,183 @@ func (b *Bom) WhereLte(field string, value interface{}) *Bom {
 	b = b.WhereConditions(field, "<=", value)
 	return b
 }
+
+// Gt matches field greater than value, unlike WhereGt the range operators on one field merge into
+// a single document: Gt("price", 10).Lt("price", 100) is {"price": {"$gt": 10, "$lt": 100}}
+func (b *Bom) Gt(field string, value interface{}) *Bom {
+	return b.addRange(field, "$gt", value)
+}
+
+func (b *Bom) Gte(field string, value interface{}) *Bom {
+	return b.addRange(field, "$gte", value)
+}
+
+func (b *Bom) Lt(field string, value interface{}) *Bom {
+	return b.addRange(field, "$lt", value)
+}
+
+func (b *Bom) Lte(field string, value interface{}) *Bom {
+	return b.addRange(field, "$lte", value)
+}
+
+// WhereDateRangeEx matches field between from and to, each end included or not. Adjacent reports should use
+// an included start and an excluded end, [from, to), so a record on a boundary is counted once.
+func (b *Bom) WhereDateRangeEx(field string, from, to time.Time, inclusiveStart, inclusiveEnd bool) *Bom {
+	if inclusiveStart {
+		b.Gte(field, from)
+	} else {
+		b.Gt(field, from)
+	}
+	if inclusiveEnd {
+		return b.Lte(field, to)
+	}
+	return b.Lt(field, to)
+}
+
+// WhereDateRange matches field within [from, to], both ends included
+func (b *Bom) WhereDateRange(field string, from, to time.Time) *Bom {
+	return b.WhereDateRangeEx(field, from, to, true, true)
+}
+
+// WhereTimestampBetween matches a BSON timestamp field within [from, to]. The server orders timestamps
+// by T and then by I, so events of the same second are told apart by their increment.
+func (b *Bom) WhereTimestampBetween(field string, from, to primitive.Timestamp) *Bom {
+	return b.Gte(field, from).Lte(field, to)
+}
+
+func (b *Bom) addRange(field string, op string, value interface{}) *Bom {
+	b.rangeConditions = append(b.rangeConditions, map[string]interface{}{"field": field, "value": primitive.M{op: value}})
+	return b
+}
+
 func (b *Bom) AddSelect(arg interface{}) *Bom {
 	b.useAggrigate = true
 	b.selectArg = append(b.selectArg, arg)
 	return b
 }
 
+// Select replaces the returned fields with arg, field names or ElemMatch projections, see Exclude for the opposite
 func (b *Bom) Select(arg ...interface{}) *Bom {
 	b.useAggrigate = true
 	b.selectArg = arg
 	return b
 }
 
+// Exclude leaves fields out of the result, it can't be combined with Select except for "_id"
+func (b *Bom) Exclude(fields ...string) *Bom {
+	return b.ExcludeIf(true, fields...)
+}
+
+func (b *Bom) checkProjection() error {
+	var included, excluded bool
+	for _, item := range b.selectArg {
+		switch v := item.(type) {
+		case string:
+			included = included || v != "_id"
+		case computedField:
+			included = true
+		case excludedField:
+			excluded = excluded || v != "_id"
+		}
+	}
+	if included && excluded {
+		return ErrMixedProjection
+	}
+	return nil
+}
+
+// SelectIf adds fields to the selected ones only when cond holds, e.g. SelectIf(isAdmin, "email", "phone")
+func (b *Bom) SelectIf(cond bool, fields ...string) *Bom {
+	if cond {
+		for _, field := range fields {
+			b.AddSelect(field)
+		}
+	}
+	return b
+}
+
+// ExcludeIf leaves fields out of the result only when cond holds. A projection can't mix inclusion and exclusion
+// (except for _id), the query fails with ErrMixedProjection when it is combined with Select of other fields.
+func (b *Bom) ExcludeIf(cond bool, fields ...string) *Bom {
+	if cond {
+		for _, field := range fields {
+			b.AddSelect(excludedField(field))
+		}
+	}
+	return b
+}
+
+// SelectArrayLen adds the length of the array field to the selected fields as as, 0 when the field is missing or not an array,
+// so the array itself is never transferred. Aggregation expressions in a find projection require MongoDB 4.4+.
+func (b *Bom) SelectArrayLen(field, as string) *Bom {
+	return b.AddSelect(computedField{as: as, expr: primitive.M{"$cond": primitive.A{
+		primitive.M{"$isArray": "$" + field},
+		primitive.M{"$size": "$" + field},
+		0,
+	}}})
+}
+
+// Exists matches documents that have field, or with exists false those that don't.
+// A field set to null exists, combine with WhereNotEq(field, nil) to skip those too.
+func (b *Bom) Exists(field string, exists bool) *Bom {
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": primitive.M{"$exists": exists}})
+	return b
+}
+
+// Like matches field against the regular expression pattern ignoring case, e.g. Like("name", "^john").
+// pattern is not escaped, pass user input through regexp.QuoteMeta. Only a case-sensitive prefix
+// ("^john" with LikeCase) can use an index, see StartsWithCI for a case-insensitive prefix that can.
+func (b *Bom) Like(field, pattern string) *Bom {
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": primitive.Regex{Pattern: pattern, Options: "i"}})
+	return b
+}
+
+// LikeCase is Like matching case-sensitively
+func (b *Bom) LikeCase(field, pattern string) *Bom {
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": primitive.Regex{Pattern: pattern}})
+	return b
+}
+
+// StartsWithCI matches a case-insensitive prefix with an anchored range instead of a regex,
+// it runs with a strength 2 collation and is only fast with an index created with the same collation:
+// createIndex({field: 1}, {collation: {locale: "en", strength: 2}}).
+// A collation already set with WithCollation is kept, the query fails when it is case-sensitive.
+func (b *Bom) StartsWithCI(field, prefix string) *Bom {
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": primitive.D{
+		{Key: "$gte", Value: prefix},
+		{Key: "$lt", Value: prefix + "\uffff"},
+	}})
+	switch {
+	case b.collation == nil:
+		b.collation = &options.Collation{Locale: "en", Strength: 2}
+	case b.collation.Strength != 1 && b.collation.Strength != 2:
+		b.setErr(fmt.Errorf("StartsWithCI on %s needs a collation of strength 1 or 2, got %d", field, b.collation.Strength))
+	}
+	return b
+}
+
+// WhereDateDiff compares the difference between two date fields, endDate fieldB minus startDate fieldA,
+// counted in unit ("day", "hour", ...), with value: WhereDateDiff("createdAt", "expiresAt", "day", "<=", 7).
+// It uses $expr with $dateDiff which requires MongoDB 5.0+ and can't use an index, so combine it with indexed conditions.
+func (b *Bom) WhereDateDiff(fieldA, fieldB string, unit string, op string, value int) *Bom {
+	dateDiff := primitive.M{"$dateDiff": primitive.M{"startDate": "$" + fieldA, "endDate": "$" + fieldB, "unit": unit}}
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": "$expr", "value": primitive.M{exprOperator(op): primitive.A{dateDiff, value}}})
+	return b
+}
+
+// WhereExprNow compares field with the server clock: WhereExprNow("expiresAt", "<") matches what already expired,
+// which avoids client clock skew. $$NOW only exists in aggregation expressions, hence the $expr (MongoDB 4.2+).
+func (b *Bom) WhereExprNow(field string, op string) *Bom {
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": "$expr", "value": primitive.M{exprOperator(op): primitive.A{"$" + field, "$$NOW"}}})
+	return b
+}
+
+// WithProjection sets a raw projection document for the query, Select takes precedence over it
+func (b *Bom) WithProjection(projection interface{}) *Bom {
+	b.projection = projection
+	return b
+}
+
 func (b *Bom) WhereConditions(field string, conditions string, value interface{}) *Bom {
 	switch conditions {
 	case ">":
@@ -260,6 +789,99 @@ func (b *Bom) WhereConditions(field string, conditions string, value interface{}
 	return b
 }
 
+// WhereElemMatch matches documents where at least one element of the array field satisfies every condition of the group
+func (b *Bom) WhereElemMatch(field string, cond func(*Group)) *Bom {
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": primitive.M{"$elemMatch": newGroup(cond).build()}})
+	return b
+}
+
+// WhereNotElemMatch matches documents where no element of the array field satisfies the group,
+// arrays that are empty or missing match too.
+// This is not the same as WhereElemMatch with negated conditions, which matches when at least one element fails them.
+func (b *Bom) WhereNotElemMatch(field string, cond func(*Group)) *Bom {
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": primitive.M{"$not": primitive.M{"$elemMatch": newGroup(cond).build()}}})
+	return b
+}
+
+// CreatedWithin matches documents created in the last d, by default from the creation time
+// embedded in an ObjectID _id, or by the date field set with SetCreatedField
+func (b *Bom) CreatedWithin(d time.Duration) *Bom {
+	cutoff := time.Now().Add(-d)
+	if b.createdField != "" {
+		return b.WhereGte(b.createdField, cutoff)
+	}
+	return b.WhereGte("_id", objectIDAt(cutoff))
+}
+
+// objectIDAt is the smallest ObjectID of t's second, only the timestamp bytes set, so a $gte on it
+// matches every id generated from that second on
+func objectIDAt(t time.Time) primitive.ObjectID {
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(t.Unix()))
+	return id
+}
+
+// WhereItemCount compares the number of elements of the array field with n. Equality is a plain {field: {$size: n}},
+// any other operator needs $expr with $size (a missing field counts 0). Neither can use an index,
+// for range queries on hot paths keep a counter field next to the array, e.g. $inc it with every $push,
+// and filter on that instead.
+func (b *Bom) WhereItemCount(field string, op string, n int) *Bom {
+	operator := exprOperator(op)
+	if operator == "$eq" {
+		b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": primitive.M{"$size": n}})
+		return b
+	}
+	size := primitive.M{"$size": primitive.M{"$ifNull": primitive.A{"$" + field, primitive.A{}}}}
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": "$expr", "value": primitive.M{operator: primitive.A{size, n}}})
+	return b
+}
+
+// WhereTuples matches documents whose fields equal any one of tuples, in the order of fields:
+// WhereTuples([]string{"tenantId", "slug"}, [][]interface{}{{t1, "a"}, {t2, "b"}}) batch-loads composite keys in one query.
+// A tuple whose length differs from fields makes the query fail, an empty tuples matches nothing.
+func (b *Bom) WhereTuples(fields []string, tuples [][]interface{}) *Bom {
+	or := make([]primitive.M, 0, len(tuples))
+	for i, tuple := range tuples {
+		if len(tuple) != len(fields) {
+			b.setErr(fmt.Errorf("tuple %d has %d values for %d fields", i, len(tuple), len(fields)))
+			return b
+		}
+		match := make(primitive.M, len(fields))
+		for j, field := range fields {
+			match[field] = tuple[j]
+		}
+		or = append(or, match)
+	}
+	if b.allowedFields != nil {
+		for _, field := range fields {
+			b.setErr(b.allowField(field))
+		}
+	}
+	if len(or) == 0 {
+		or = append(or, primitive.M{"_id": primitive.M{"$in": primitive.A{}}})
+	}
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": "$or", "value": or})
+	return b
+}
+
+// WhereArrayIndex matches documents whose array field holds value at the zero-based index,
+// WhereArrayIndex("items", 2, x) is {"items.2": x}. A negative index makes the query fail.
+func (b *Bom) WhereArrayIndex(field string, index int, value interface{}) *Bom {
+	if index < 0 {
+		b.setErr(fmt.Errorf("WhereArrayIndex on %s: negative index %d", field, index))
+		return b
+	}
+	b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": fmt.Sprintf("%s.%d", field, index), "value": value})
+	return b
+}
+
+// OrGroup adds the conditions of the group, ANDed together, as one branch of the $or:
+// OrGroup(func(g *Group) { g.WhereEq("a", 1).WhereEq("b", 2) }).OrWhereEq("c", 3) is (a=1 AND b=2) OR c=3
+func (b *Bom) OrGroup(fn func(*Group)) *Bom {
+	b.orConditions = append(b.orConditions, map[string]interface{}{"field": "$group", "value": newGroup(fn)})
+	return b
+}
+
 func (b *Bom) OrWhereConditions(field string, conditions string, value interface{}) *Bom {
 	switch conditions {
 	case ">":
@@ -276,6 +898,30 @@ func (b *Bom) OrWhereConditions(field string, conditions string, value interface
 	return b
 }
 
+// NorWhereConditions adds a clause to the top-level $nor, a document matches only if it fails every $nor clause
+func (b *Bom) NorWhereConditions(field string, conditions string, value interface{}) *Bom {
+	switch conditions {
+	case ">":
+		b.norConditions = append(b.norConditions, map[string]interface{}{"field": field, "value": primitive.D{{Key: "$gt", Value: value}}})
+	case ">=":
+		b.norConditions = append(b.norConditions, map[string]interface{}{"field": field, "value": primitive.D{{Key: "$gte", Value: value}}})
+	case "<":
+		b.norConditions = append(b.norConditions, map[string]interface{}{"field": field, "value": primitive.D{{Key: "$lt", Value: value}}})
+	case "<=":
+		b.norConditions = append(b.norConditions, map[string]interface{}{"field": field, "value": primitive.D{{Key: "$lte", Value: value}}})
+	case "!=":
+		b.norConditions = append(b.norConditions, map[string]interface{}{"field": field, "value": primitive.D{{Key: "$ne", Value: value}}})
+	default:
+		b.norConditions = append(b.norConditions, map[string]interface{}{"field": field, "value": value})
+	}
+	return b
+}
+
+func (b *Bom) NorWhere(field string, value interface{}) *Bom {
+	b = b.NorWhereConditions(field, "=", value)
+	return b
+}
+
 func (b *Bom) SetUpdateOptions(opts ...*options.UpdateOptions) *Bom {
 	for _, value := range opts {
 		b.updateOptions = append(b.updateOptions, value)
@@ -334,16 +980,43 @@ func (b *Bom) OrWhereLte(field string, value interface{}) *Bom {
 	return b
 }
 
+// NotGroup excludes the documents matching all conditions of the group at once, NOT (a=1 AND b=2),
+// through $nor. A document matching only some of them is kept.
+func (b *Bom) NotGroup(fn func(*Group)) *Bom {
+	b.norConditions = append(b.norConditions, map[string]interface{}{"field": "$group", "value": newGroup(fn)})
+	return b
+}
+
+// Not matches documents whose field is not value ($ne), repeated calls for the same field become one $nin
 func (b *Bom) Not(field string, value interface{}) *Bom {
 	b.notConditions = append(b.notConditions, map[string]interface{}{"field": field, "value": value})
 	return b
 }
 
+// InWhere matches documents whose field is one of value. Repeated calls for the same field are merged
+// into a single $in of all the values, a document matches if it has any of them.
 func (b *Bom) InWhere(field string, value interface{}) *Bom {
 	b.inConditions = append(b.inConditions, map[string]interface{}{"field": field, "value": value})
 	return b
 }
 
+// InWhereChunked is an $in for very large value lists: List, All, One and ListMaps run one query per
+// chunkSize values and merge the results without duplicates by _id, so no single query hits the BSON size limit.
+// Sort and limits apply per chunk. Every other method sends all values in a single $in.
+func (b *Bom) InWhereChunked(field string, values []interface{}, chunkSize int) *Bom {
+	if chunkSize < 1 {
+		chunkSize = len(values)
+	}
+	b.inChunks = &inChunks{field: field, values: values, size: chunkSize}
+	return b
+}
+
+// NotIn matches documents whose field is none of value, repeated calls for the same field are merged
+// and an InWhere on the same field ends up in the same document: {field: {"$in": [...], "$nin": [...]}}
+func (b *Bom) NotIn(field string, value interface{}) *Bom {
+	return b.NotInWhere(field, value)
+}
+
 func (b *Bom) NotInWhere(field string, value interface{}) *Bom {
 	b.notInConditions = append(b.notInConditions, map[string]interface{}{"field": field, "value": value})
 	return b
@@ -361,6 +1034,10 @@ func (b *Bom) buildProjection() (interface{}, bool) {
 		switch v := item.(type) {
 		case string:
 			result[v] = 1
+		case computedField:
+			result[v.as] = v.expr
+		case excludedField:
+			result[string(v)] = 0
 		case ElemMatch:
 			if vo, ok := v.Val.(ElemMatch); ok {
 				var sub = make(primitive.M)
@@ -369,59 +1046,441 @@ func (b *Bom) buildProjection() (interface{}, bool) {
 			}
 		}
 	}
+	var projection interface{}
 	if len(result) > 0 {
-		return result, true
+		projection = result
+	} else if b.projection != nil {
+		projection = b.projection
+	}
+	if projection == nil {
+		return b.defaultProjection, b.defaultProjection != nil
+	}
+	if b.defaultProjection == nil {
+		return projection, true
+	}
+	// an inclusion replaces the default projection, exclusions only add to it
+	exclusions, ok := projectionDoc(projection)
+	if !ok || !isExclusion(exclusions) {
+		return projection, true
 	}
-	return nil, false
+	defaults, ok := projectionDoc(b.defaultProjection)
+	if !ok {
+		return projection, true
+	}
+	return mergeExclusions(defaults, exclusions), true
 }
 
-func (b *Bom) buildCondition() interface{} {
-	result := make(primitive.M)
-	if len(b.whereConditions) > 0 {
-		var query []primitive.M
-		for _, cnd := range b.whereConditions {
-			field := cnd["field"]
-			value := cnd["value"]
-			query = append(query, primitive.M{field.(string): value})
-		}
-		result["$and"] = query
+func projectionDoc(projection interface{}) (primitive.M, bool) {
+	if doc, ok := projection.(primitive.M); ok {
+		return doc, true
 	}
-	if len(b.orConditions) > 0 {
+	raw, err := bson.Marshal(projection)
+	if err != nil {
+		return nil, false
+	}
+	var doc primitive.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// isExclusion tells whether the projection only leaves fields out, _id included
+func isExclusion(projection primitive.M) bool {
+	if len(projection) == 0 {
+		return false
+	}
+	for _, value := range projection {
+		if !isExcluded(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func isExcluded(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return !v
+	case int:
+		return v == 0
+	case int32:
+		return v == 0
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+// mergeExclusions hides the excluded fields on top of the default projection: they are added to an exclusion
+// default and removed from an inclusion one, which never ends up returning every field
+func mergeExclusions(defaults, exclusions primitive.M) primitive.M {
+	merged := make(primitive.M, len(defaults)+len(exclusions))
+	for field, value := range defaults {
+		merged[field] = value
+	}
+	if isExclusion(defaults) {
+		for field := range exclusions {
+			merged[field] = 0
+		}
+		return merged
+	}
+	included := 0
+	for field := range exclusions {
+		if field == "_id" {
+			merged[field] = 0
+			continue
+		}
+		delete(merged, field)
+	}
+	for field := range merged {
+		if field != "_id" {
+			included++
+		}
+	}
+	if included == 0 {
+		return primitive.M{"_id": 1}
+	}
+	return merged
+}
+
+func (b *Bom) buildCondition() interface{} {
+	result := make(primitive.M)
+	if len(b.whereConditions) > 0 || b.inChunks != nil {
+		var query []primitive.M
+		elems := make(map[string]*Group)
+		for _, cnd := range b.whereConditions {
+			field := cnd["field"]
+			value := cnd["value"]
+			if path, rest, ok := b.arrayPath(field.(string)); ok {
+				if _, exists := elems[path]; !exists {
+					elems[path] = &Group{}
+				}
+				elems[path].conditions = append(elems[path].conditions, map[string]interface{}{"field": rest, "value": value})
+				continue
+			}
+			query = append(query, primitive.M{field.(string): value})
+		}
+		for _, path := range b.arrayPaths {
+			if group, ok := elems[path]; ok {
+				query = append(query, primitive.M{path: primitive.M{"$elemMatch": group.build()}})
+			}
+		}
+		b.checkArrayPaths()
+		if b.inChunks != nil {
+			query = append(query, b.inChunks.condition())
+		}
+		result["$and"] = query
+	}
+	if len(b.orConditions) > 0 {
 		var query []primitive.M
 		for _, cnd := range b.orConditions {
 			field := cnd["field"]
 			value := cnd["value"]
+			if group, ok := value.(*Group); ok {
+				query = append(query, group.build())
+				continue
+			}
 			query = append(query, primitive.M{field.(string): value})
 		}
 		result["$or"] = query
 	}
-	if len(b.inConditions) > 0 {
-		for _, cnd := range b.inConditions {
+	if len(b.norConditions) > 0 {
+		var query []primitive.M
+		for _, cnd := range b.norConditions {
 			field := cnd["field"]
 			value := cnd["value"]
-			result[field.(string)] = primitive.M{"$in": value}
+			if group, ok := value.(*Group); ok {
+				query = append(query, group.buildAnd())
+				continue
+			}
+			query = append(query, primitive.M{field.(string): value})
+		}
+		result["$nor"] = query
+	}
+	if len(b.inConditions) > 0 {
+		for field, value := range mergeValues(b.inConditions) {
+			result[field] = primitive.M{"$in": value}
 		}
 	}
 	if len(b.notInConditions) > 0 {
-		for _, cnd := range b.notInConditions {
-			field := cnd["field"]
-			value := cnd["value"]
-			result[field.(string)] = primitive.M{"$nin": value}
+		for field, value := range mergeValues(b.notInConditions) {
+			addOperator(result, field, "$nin", value)
+		}
+	}
+	b.addEncryptedConditions(result)
+	for _, cnd := range b.rangeConditions {
+		for op, value := range cnd["value"].(primitive.M) {
+			addOperator(result, cnd["field"].(string), op, value)
+		}
+	}
+	if len(b.notConditions) > 0 {
+		for field, value := range mergeValues(b.notConditions) {
+			if values, ok := value.([]interface{}); ok {
+				addOperator(result, field, "$nin", values)
+			} else {
+				addOperator(result, field, "$ne", value)
+			}
 		}
 	}
 	return result
 }
 
+// addOperator sets {field: {op: value}}, next to the operators the field already has when op isn't one of them,
+// otherwise as one more $and clause
+func addOperator(result primitive.M, field string, op string, value interface{}) {
+	existing, ok := result[field]
+	if !ok {
+		result[field] = primitive.M{op: value}
+		return
+	}
+	if ops, ok := existing.(primitive.M); ok {
+		if _, taken := ops[op]; !taken {
+			ops[op] = value
+			return
+		}
+	}
+	and, _ := result["$and"].([]primitive.M)
+	result["$and"] = append(and, primitive.M{field: primitive.M{op: value}})
+}
+
+// mergeValues concatenates the value lists of conditions on the same field, a single value is kept as is
+func mergeValues(conditions []map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, cnd := range conditions {
+		field := cnd["field"].(string)
+		value := cnd["value"]
+		existing, ok := merged[field]
+		if !ok {
+			merged[field] = value
+			continue
+		}
+		merged[field] = append(toSlice(existing), toSlice(value)...)
+	}
+	return merged
+}
+
+func toSlice(value interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{value}
+	}
+	values := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		values = append(values, v.Index(i).Interface())
+	}
+	return values
+}
+
 func (b *Bom) Mongo() *mongo.Collection {
-	return b.client.Database(b.dbName).Collection(b.dbCollection)
+	return b.query()
+}
+
+func (b *Bom) query(opts ...*options.CollectionOptions) *mongo.Collection {
+	if b.registry != nil {
+		opts = append([]*options.CollectionOptions{options.Collection().SetRegistry(b.registry)}, opts...)
+	}
+	if wc := b.getWriteConcern(); wc != nil {
+		opts = append([]*options.CollectionOptions{options.Collection().SetWriteConcern(wc)}, opts...)
+	}
+	return b.client.Database(b.dbName).Collection(b.dbCollection, opts...)
+}
+
+func (b *Bom) getWriteConcern() *writeconcern.WriteConcern {
+	if b.wTimeout <= 0 {
+		return b.writeConcern
+	}
+	if b.writeConcern == nil {
+		return writeconcern.New(writeconcern.WTimeout(b.wTimeout))
+	}
+	return b.writeConcern.WithOptions(writeconcern.WTimeout(b.wTimeout))
+}
+
+// readQuery is the collection used by read methods, it carries the read preference which must never reach writes
+func (b *Bom) readQuery() *mongo.Collection {
+	if b.readPreference != nil {
+		return b.query(options.Collection().SetReadPreference(b.readPreference))
+	}
+	return b.query()
 }
 
+func (b *Bom) getReadTimeout() time.Duration {
+	if b.readQueryTimeout > 0 {
+		return b.readQueryTimeout
+	}
+	return b.getQueryTimeout()
+}
+
+func (b *Bom) getWriteTimeout() time.Duration {
+	if b.writeQueryTimeout > 0 {
+		return b.writeQueryTimeout
+	}
+	return b.getQueryTimeout()
+}
+
+// getQueryTimeout falls back to DefaultQueryTimeout when the timeout was set to zero
+func (b *Bom) getQueryTimeout() time.Duration {
+	if b.queryTimeout > 0 {
+		return b.queryTimeout
+	}
+	return DefaultQueryTimeout
+}
+
+func (b *Bom) newFindOptions(ctx context.Context) *options.FindOptions {
+	findOptions := options.Find()
+	if b.batchSize > 0 {
+		findOptions.SetBatchSize(b.batchSize)
+	}
+	if comment, ok := b.getRequestComment(ctx); ok {
+		findOptions.SetComment(comment)
+	}
+	if b.collation != nil {
+		findOptions.SetCollation(b.collation)
+	}
+	if b.hint != nil {
+		findOptions.SetHint(b.hint)
+	}
+	return findOptions
+}
+
+func (b *Bom) getRequestComment(ctx context.Context) (string, bool) {
+	if b.requestIDFunc == nil {
+		return "", false
+	}
+	comment := b.requestIDFunc(ctx)
+	return comment, comment != ""
+}
+
+func (b *Bom) getFindOneOptions(ctx context.Context) []*options.FindOneOptions {
+	var opts []*options.FindOneOptions
+	if comment, ok := b.getRequestComment(ctx); ok {
+		opts = append(opts, options.FindOne().SetComment(comment))
+	}
+	if projection, ok := b.buildProjection(); ok {
+		opts = append(opts, options.FindOne().SetProjection(projection))
+	}
+	opts = append(opts, b.findOneOptions...)
+	if b.collation != nil {
+		opts = append(opts, options.FindOne().SetCollation(b.collation))
+	}
+	if b.hint != nil {
+		opts = append(opts, options.FindOne().SetHint(b.hint))
+	}
+	return opts
+}
+
+func (b *Bom) newCountOptions() *options.CountOptions {
+	countOptions := options.Count()
+	if b.collation != nil {
+		countOptions.SetCollation(b.collation)
+	}
+	if b.hint != nil {
+		countOptions.SetHint(b.hint)
+	}
+	return countOptions
+}
+
+func (b *Bom) getAggregateOptions() []*options.AggregateOptions {
+	opts := append([]*options.AggregateOptions{}, b.aggregateOptions...)
+	if b.batchSize > 0 {
+		opts = append(opts, options.Aggregate().SetBatchSize(b.batchSize))
+	}
+	if b.maxAwaitTime > 0 {
+		opts = append(opts, options.Aggregate().SetMaxAwaitTime(b.maxAwaitTime))
+	}
+	if b.collation != nil {
+		opts = append(opts, options.Aggregate().SetCollation(b.collation))
+	}
+	if b.hint != nil {
+		opts = append(opts, options.Aggregate().SetHint(b.hint))
+	}
+	return opts
+}
+
+func (b *Bom) getInsertOptions() []*options.InsertOneOptions {
+	opts := append([]*options.InsertOneOptions{}, b.insertOptions...)
+	if b.bypassValidation {
+		opts = append(opts, options.InsertOne().SetBypassDocumentValidation(true))
+	}
+	return opts
+}
+
+func (b *Bom) newInsertManyOptions() *options.InsertManyOptions {
+	insertManyOptions := options.InsertMany()
+	if b.bypassValidation {
+		insertManyOptions.SetBypassDocumentValidation(true)
+	}
+	return insertManyOptions
+}
+
+func (b *Bom) getUpdateOptions() []*options.UpdateOptions {
+	opts := append([]*options.UpdateOptions{}, b.updateOptions...)
+	if b.bypassValidation {
+		opts = append(opts, options.Update().SetBypassDocumentValidation(true))
+	}
+	if b.upsert {
+		opts = append(opts, options.Update().SetUpsert(true))
+	}
+	if b.collation != nil {
+		opts = append(opts, options.Update().SetCollation(b.collation))
+	}
+	return opts
+}
+
+func (b *Bom) newDeleteOptions() *options.DeleteOptions {
+	deleteOptions := options.Delete()
+	if b.collation != nil {
+		deleteOptions.SetCollation(b.collation)
+	}
+	return deleteOptions
+}
+
+func (b *Bom) getFindOneAndUpdateOptions() []*options.FindOneAndUpdateOptions {
+	var opts []*options.FindOneAndUpdateOptions
+	if projection, ok := b.buildProjection(); ok {
+		opts = append(opts, options.FindOneAndUpdate().SetProjection(projection))
+	}
+	opts = append(opts, b.findOneAndUpdateOptions...)
+	if b.bypassValidation {
+		opts = append(opts, options.FindOneAndUpdate().SetBypassDocumentValidation(true))
+	}
+	if b.collation != nil {
+		opts = append(opts, options.FindOneAndUpdate().SetCollation(b.collation))
+	}
+	return opts
+}
+
+func (b *Bom) getFindOneAndReplaceOptions() []*options.FindOneAndReplaceOptions {
+	var opts []*options.FindOneAndReplaceOptions
+	if projection, ok := b.buildProjection(); ok {
+		opts = append(opts, options.FindOneAndReplace().SetProjection(projection))
+	}
+	if b.bypassValidation {
+		opts = append(opts, options.FindOneAndReplace().SetBypassDocumentValidation(true))
+	}
+	if b.collation != nil {
+		opts = append(opts, options.FindOneAndReplace().SetCollation(b.collation))
+	}
+	return opts
+}
+
+func (b *Bom) newBulkWriteOptions() *options.BulkWriteOptions {
+	bulkWriteOptions := options.BulkWrite()
+	if b.bypassValidation {
+		bulkWriteOptions.SetBypassDocumentValidation(true)
+	}
+	return bulkWriteOptions
+}
+
+// getTotalPages counts the last partial page too, a size of 0 is taken as DefaultSize
 func (b *Bom) getTotalPages() int32 {
-	d := float64(b.pagination.TotalCount) / float64(b.pagination.Size)
-	if d < 0 {
-		d = 1
+	size := b.pagination.Size
+	if size <= 0 {
+		size = DefaultSize
 	}
-	return int32(math.Ceil(d))
+	return int32(math.Ceil(float64(b.pagination.TotalCount) / float64(size)))
 }
 
 func (b *Bom) getPagination(total int32, page int32, size int32) *Pagination {
@@ -433,6 +1492,8 @@ func (b *Bom) getPagination(total int32, page int32, size int32) *Pagination {
 		b.pagination.Size = size
 	}
 	b.pagination.TotalPages = b.getTotalPages()
+	b.pagination.HasNext = b.pagination.CurrentPage < b.pagination.TotalPages
+	b.pagination.HasPrevious = b.pagination.CurrentPage > 1
 	return b.pagination
 }
 
@@ -504,37 +1565,69 @@ func (b *Bom) calculateOffset(page, size int32) (limit int32, offset int32) {
 	return
 }
 
-func (b *Bom) getSort(sorts []*Sort) (map[string]interface{}, bool) {
-	sortMap := make(map[string]interface{})
-	if len(sorts) > 0 {
-		for _, sort := range sorts {
-			if len(sort.Field) > 0 {
-				sortMap[strings.ToLower(sort.Field)] = 1
-				if len(sort.Type) > 0 {
-					if val, ok := mType[strings.ToLower(sort.Type)]; ok {
-						sortMap[strings.ToLower(sort.Field)] = val
-					}
-				}
-				return sortMap, true
-			}
+// getSort builds the sort document in the order the sorts were added, keyed by the field as given,
+// Type only picks the direction ("asc" by default). A field sorted twice keeps its first direction.
+func (b *Bom) getSort(sorts []*Sort) (bson.D, bool) {
+	var sortDoc bson.D
+	seen := make(map[string]bool)
+	for _, sort := range sorts {
+		if len(sort.Field) == 0 || seen[sort.Field] {
+			continue
+		}
+		seen[sort.Field] = true
+		var direction int32 = 1
+		if val, ok := mType[strings.ToLower(sort.Type)]; ok {
+			direction = val
 		}
+		sortDoc = append(sortDoc, primitive.E{Key: sort.Field, Value: direction})
 	}
-	return sortMap, false
+	return sortDoc, len(sortDoc) > 0
 }
 
+// getCondition returns the WithCondition document, the fluent conditions, or both under $and when both are set
 func (b *Bom) getCondition() interface{} {
-	if b.condition != nil {
-		return b.condition
+	var fluent primitive.M
+	if bc, ok := b.buildCondition().(primitive.M); ok {
+		fluent = bc
 	}
-	bc := b.buildCondition()
-	if bc != nil {
-		if val, ok := bc.(primitive.M); ok {
-			return val
+	if b.condition != nil {
+		condition := b.condition
+		if len(fluent) > 0 {
+			condition = primitive.M{"$and": []interface{}{b.condition, fluent}}
 		}
+		b.checkShardKey(condition)
+		return condition
+	}
+	if fluent != nil {
+		b.checkShardKey(fluent)
+		return fluent
 	}
 	return primitive.M{}
 }
 
+// BuildFilter returns the filter the builder sends to the server, e.g. to reuse it as a $match stage
+func (b *Bom) BuildFilter() (bson.M, error) {
+	condition := b.getCondition()
+	if filter, ok := condition.(primitive.M); ok {
+		return filter, nil
+	}
+	data, err := bson.Marshal(condition)
+	if err != nil {
+		return nil, fmt.Errorf("condition of type %T is not a document: %w", condition, err)
+	}
+	var filter bson.M
+	if err := bson.Unmarshal(data, &filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (b *Bom) warnf(format string, v ...interface{}) {
+	if b.logger != nil {
+		b.logger.Printf(format, v...)
+	}
+}
+
 //Deprecated: method works not correctly user bom generator (https://github.com/cjp2600/protoc-gen-bom)
 func (b *Bom) Update(entity interface{}) (*mongo.UpdateResult, error) {
 	mp, _ := b.structToMap(entity)
@@ -548,22 +1641,136 @@ func (b *Bom) Update(entity interface{}) (*mongo.UpdateResult, error) {
 	}
 
 	upResult := primitive.D{
-		{"$set", eRes},
-		{"$currentDate", primitive.D{{"updatedat", true}}},
+		{Key: "$set", Value: eRes},
+		{Key: "$currentDate", Value: primitive.D{{Key: "updatedat", Value: true}}},
 	}
 
 	return b.UpdateRaw(upResult)
 }
 
 func (b *Bom) UpdateRaw(update interface{}) (*mongo.UpdateResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	res, err := b.Mongo().UpdateOne(ctx, b.getCondition(), update, b.updateOptions...)
+	return b.UpdateRawWithContext(context.Background(), update)
+}
+
+// UpdateRawWithContext is UpdateRaw bounded by ctx as well as the write timeout
+func (b *Bom) UpdateRawWithContext(ctx context.Context, update interface{}) (*mongo.UpdateResult, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	if err := validateUpdate(update); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	res, err := b.Mongo().UpdateOne(ctx, b.getCondition(), update, b.getUpdateOptions()...)
 	return res, err
 }
 
+// WithUpsert makes UpdateRaw, UpdateMany and ReplaceOne insert a document when nothing matches,
+// the result's UpsertedID is set in that case
+func (b *Bom) WithUpsert() *Bom {
+	b.upsert = true
+	return b
+}
+
+// UpdateMany applies update to every document matching the conditions
+func (b *Bom) UpdateMany(update interface{}) (*mongo.UpdateResult, error) {
+	return b.UpdateManyWithContext(context.Background(), update)
+}
+
+// UpdateManyWithContext is UpdateMany bounded by ctx as well as the write timeout
+func (b *Bom) UpdateManyWithContext(ctx context.Context, update interface{}) (*mongo.UpdateResult, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	if err := validateUpdate(update); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	return b.Mongo().UpdateMany(ctx, b.getCondition(), update, b.getUpdateOptions()...)
+}
+
+// ReplaceOne replaces the first matching document with replacement, fields missing from it are gone afterwards.
+// Pass options.Replace().SetUpsert(true) to insert replacement when nothing matches.
+func (b *Bom) ReplaceOne(replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	return b.ReplaceOneWithContext(context.Background(), replacement, opts...)
+}
+
+// ReplaceOneWithContext is ReplaceOne bounded by ctx as well as the write timeout
+func (b *Bom) ReplaceOneWithContext(ctx context.Context, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	if b.bypassValidation {
+		opts = append([]*options.ReplaceOptions{options.Replace().SetBypassDocumentValidation(true)}, opts...)
+	}
+	if b.upsert {
+		opts = append([]*options.ReplaceOptions{options.Replace().SetUpsert(true)}, opts...)
+	}
+	if b.collation != nil {
+		opts = append([]*options.ReplaceOptions{options.Replace().SetCollation(b.collation)}, opts...)
+	}
+	return b.Mongo().ReplaceOne(ctx, b.getCondition(), replacement, opts...)
+}
+
 func (b *Bom) InsertOne(document interface{}) (*mongo.InsertOneResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	return b.Mongo().InsertOne(ctx, document, b.insertOptions...)
+	return b.InsertOneWithContext(context.Background(), document)
+}
+
+// InsertOneWithContext is InsertOne bounded by ctx as well as the write timeout
+func (b *Bom) InsertOneWithContext(ctx context.Context, document interface{}) (*mongo.InsertOneResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	return b.Mongo().InsertOne(ctx, document, b.getInsertOptions()...)
+}
+
+// InsertOneReturning inserts document and decodes the stored document, with the server generated fields, into out.
+// The insert and the read by _id share a causally consistent session, so the read sees the write even when
+// it goes to a secondary, as long as the write and read concerns are majority.
+func (b *Bom) InsertOneReturning(document interface{}, out interface{}) error {
+	return b.InsertOneReturningWithContext(context.Background(), document, out)
+}
+
+// InsertOneReturningWithContext is InsertOneReturning bounded by ctx as well as the write timeout
+func (b *Bom) InsertOneReturningWithContext(ctx context.Context, document interface{}, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	return b.client.UseSessionWithOptions(ctx, options.Session().SetCausalConsistency(true), func(sc mongo.SessionContext) error {
+		res, err := b.Mongo().InsertOne(sc, document, b.getInsertOptions()...)
+		if err != nil {
+			return err
+		}
+		return b.readQuery().FindOne(sc, primitive.M{"_id": res.InsertedID}).Decode(out)
+	})
+}
+
+// InsertIfAbsent inserts document only when nothing matches the conditions, an existing match is left untouched.
+// It is a single upsert with $setOnInsert, so unlike find-then-insert it is safe under concurrency
+// as long as the conditions are backed by a unique index.
+func (b *Bom) InsertIfAbsent(document interface{}) (inserted bool, err error) {
+	return b.InsertIfAbsentWithContext(context.Background(), document)
+}
+
+// InsertIfAbsentWithContext is InsertIfAbsent with every attempt bounded by ctx as well as the write timeout
+func (b *Bom) InsertIfAbsentWithContext(ctx context.Context, document interface{}) (inserted bool, err error) {
+	if err := b.Err(); err != nil {
+		return false, err
+	}
+	update := primitive.D{{Key: "$setOnInsert", Value: document}}
+	err = retryUpsert(func() error {
+		ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+		defer cancel()
+		res, err := b.Mongo().UpdateOne(ctx, b.getCondition(), update, append(b.getUpdateOptions(), options.Update().SetUpsert(true))...)
+		if err != nil {
+			return err
+		}
+		inserted = res.UpsertedCount == 1
+		return nil
+	})
+	return inserted, err
 }
 
 func (b *Bom) ConvertJsonToBson(document interface{}) (interface{}, error) {
@@ -580,39 +1787,233 @@ func (b *Bom) ConvertJsonToBson(document interface{}) (interface{}, error) {
 }
 
 func (b *Bom) InsertMany(documents []interface{}) (*mongo.InsertManyResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
+	return b.InsertManyWithContext(context.Background(), documents)
+}
+
+// InsertManyWithContext is InsertMany bounded by ctx as well as the write timeout
+func (b *Bom) InsertManyWithContext(ctx context.Context, documents []interface{}) (*mongo.InsertManyResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
 	var bsonDocuments []interface{}
 	for _, document := range documents {
 		bsonDocuments = append(bsonDocuments, document)
 	}
-	return b.Mongo().InsertMany(ctx, documents)
+	return b.Mongo().InsertMany(ctx, documents, b.newInsertManyOptions())
 }
 
 func (b *Bom) FindOne(callback func(s *mongo.SingleResult) error) error {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	s := b.Mongo().FindOne(ctx, b.getCondition(), b.findOneOptions...)
+	return b.FindOneWithContext(context.Background(), callback)
+}
+
+// FindOneWithContext is FindOne bounded by ctx as well as the read timeout
+func (b *Bom) FindOneWithContext(ctx context.Context, callback func(s *mongo.SingleResult) error) error {
+	if err := b.Err(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	s := b.readQuery().FindOne(ctx, b.getCondition(), b.getFindOneOptions(ctx)...)
 	return callback(s)
 }
 
-func (b *Bom) FindOneAndUpdate(update interface{}) *mongo.SingleResult {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	return b.Mongo().FindOneAndUpdate(ctx, b.getCondition(), update, b.findOneAndUpdateOptions...)
+// Count returns the number of documents matching the conditions without fetching them
+func (b *Bom) Count() (int64, error) {
+	return b.CountWithContext(context.Background())
+}
+
+// CountWithContext is Count bounded by ctx as well as the read timeout
+func (b *Bom) CountWithContext(ctx context.Context) (int64, error) {
+	if err := b.Err(); err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	return b.readQuery().CountDocuments(ctx, b.getCondition(), b.newCountOptions())
+}
+
+// Distinct returns the distinct values of field among the matching documents, an array field contributes
+// each of its elements. The result has to fit in a single 16MB document, use DistinctCombo for large sets.
+func (b *Bom) Distinct(field string) ([]interface{}, error) {
+	return b.DistinctWithContext(context.Background(), field)
+}
+
+// DistinctWithContext is Distinct bounded by ctx as well as the read timeout
+func (b *Bom) DistinctWithContext(ctx context.Context, field string) ([]interface{}, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	distinctOptions := options.Distinct()
+	if b.collation != nil {
+		distinctOptions.SetCollation(b.collation)
+	}
+	return b.readQuery().Distinct(ctx, field, b.getCondition(), distinctOptions)
+}
+
+// EstimatedCount returns the size of the whole collection from its metadata, ignoring the conditions.
+// It is fast but may be off after an unclean shutdown or while orphaned documents exist on a sharded cluster.
+func (b *Bom) EstimatedCount() (int64, error) {
+	return b.EstimatedCountWithContext(context.Background())
+}
+
+// EstimatedCountWithContext is EstimatedCount bounded by ctx as well as the read timeout
+func (b *Bom) EstimatedCountWithContext(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	return b.readQuery().EstimatedDocumentCount(ctx)
+}
+
+// FindOneAndUpdate atomically updates the first matching document and returns it as it was before the update,
+// pass options.FindOneAndUpdate().SetReturnDocument(options.After) for the updated one, or use ReturnAfter
+func (b *Bom) FindOneAndUpdate(update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return b.FindOneAndUpdateWithContext(context.Background(), update, opts...)
+}
+
+// FindOneAndUpdateWithContext is FindOneAndUpdate bounded by ctx as well as the write timeout
+func (b *Bom) FindOneAndUpdateWithContext(ctx context.Context, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	return b.Mongo().FindOneAndUpdate(ctx, b.singleResultFilter(), update, append(b.getFindOneAndUpdateOptions(), opts...)...)
+}
+
+// ReturnAfter makes FindOneAndUpdate return the document as it is after the update
+func ReturnAfter() *options.FindOneAndUpdateOptions {
+	return options.FindOneAndUpdate().SetReturnDocument(options.After)
+}
+
+// FindOneAndReplace atomically replaces the first matching document with replacement and returns it
+// as it was before, pass options.FindOneAndReplace().SetReturnDocument(options.After) for the new one
+func (b *Bom) FindOneAndReplace(replacement interface{}, opts ...*options.FindOneAndReplaceOptions) *mongo.SingleResult {
+	return b.FindOneAndReplaceWithContext(context.Background(), replacement, opts...)
+}
+
+// FindOneAndReplaceWithContext is FindOneAndReplace bounded by ctx as well as the write timeout
+func (b *Bom) FindOneAndReplaceWithContext(ctx context.Context, replacement interface{}, opts ...*options.FindOneAndReplaceOptions) *mongo.SingleResult {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	return b.Mongo().FindOneAndReplace(ctx, b.singleResultFilter(), replacement, append(b.getFindOneAndReplaceOptions(), opts...)...)
+}
+
+// FindOneAndUpdateGuarded applies update only if the document also satisfies guard, atomically,
+// e.g. decrement stock only while it is positive. When the guard fails the result error is ErrNotFound.
+func (b *Bom) FindOneAndUpdateGuarded(guard func(*Group), update interface{}) *mongo.SingleResult {
+	return b.FindOneAndUpdateGuardedWithContext(context.Background(), guard, update)
+}
+
+// FindOneAndUpdateGuardedWithContext is FindOneAndUpdateGuarded bounded by ctx as well as the write timeout
+func (b *Bom) FindOneAndUpdateGuardedWithContext(ctx context.Context, guard func(*Group), update interface{}) *mongo.SingleResult {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	var condition interface{} = primitive.M{"$and": []interface{}{b.getCondition(), newGroup(guard).build()}}
+	if err := b.Err(); err != nil {
+		condition = errFilter{err: err}
+	}
+	return b.Mongo().FindOneAndUpdate(ctx, condition, update, b.getFindOneAndUpdateOptions()...)
+}
+
+// UpdateOneWithDiff updates the first matching document and returns it as it was before and after the update.
+// The after state is re-read from the primary by _id right away, a concurrent write landing in between would be
+// included in it. ErrNotFound when nothing matches.
+func (b *Bom) UpdateOneWithDiff(update interface{}) (before bson.M, after bson.M, err error) {
+	return b.UpdateOneWithDiffWithContext(context.Background(), update)
+}
+
+// UpdateOneWithDiffWithContext is UpdateOneWithDiff bounded by ctx as well as the write timeout
+func (b *Bom) UpdateOneWithDiffWithContext(ctx context.Context, update interface{}) (before bson.M, after bson.M, err error) {
+	if err := b.Err(); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	opts := append(b.getFindOneAndUpdateOptions(), options.FindOneAndUpdate().SetReturnDocument(options.Before))
+	raw, err := b.Mongo().FindOneAndUpdate(ctx, b.getCondition(), update, opts...).DecodeBytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := raw.LookupErr("_id")
+	if err != nil {
+		return nil, nil, fmt.Errorf("UpdateOneWithDiff needs _id in the projection: %w", err)
+	}
+	if before, err = b.decodeMap(raw); err != nil {
+		return nil, nil, err
+	}
+	findOptions := options.FindOne()
+	if projection, ok := b.buildProjection(); ok {
+		findOptions.SetProjection(projection)
+	}
+	raw, err = b.Mongo().FindOne(ctx, primitive.M{"_id": id}, findOptions).DecodeBytes()
+	if err != nil {
+		return before, nil, err
+	}
+	after, err = b.decodeMap(raw)
+	return before, after, err
 }
 
 func (b *Bom) FindOneAndDelete() *mongo.SingleResult {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	return b.Mongo().FindOneAndDelete(ctx, b.getCondition())
+	return b.FindOneAndDeleteWithContext(context.Background())
+}
+
+// FindOneAndDeleteWithContext is FindOneAndDelete bounded by ctx as well as the write timeout
+func (b *Bom) FindOneAndDeleteWithContext(ctx context.Context) *mongo.SingleResult {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	opts := options.FindOneAndDelete()
+	if projection, ok := b.buildProjection(); ok {
+		opts.SetProjection(projection)
+	}
+	if b.collation != nil {
+		opts.SetCollation(b.collation)
+	}
+	return b.Mongo().FindOneAndDelete(ctx, b.singleResultFilter(), opts)
+}
+
+// DeleteOne deletes the first document matching the conditions
+func (b *Bom) DeleteOne() (*mongo.DeleteResult, error) {
+	return b.DeleteOneWithContext(context.Background())
+}
+
+// DeleteOneWithContext is DeleteOne bounded by ctx as well as the write timeout
+func (b *Bom) DeleteOneWithContext(ctx context.Context) (*mongo.DeleteResult, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	return b.Mongo().DeleteOne(ctx, b.getCondition(), b.newDeleteOptions())
 }
 
 func (b *Bom) DeleteMany() (*mongo.DeleteResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	return b.Mongo().DeleteMany(ctx, b.getCondition())
+	return b.DeleteManyWithContext(context.Background())
+}
+
+// DeleteManyWithContext is DeleteMany bounded by ctx as well as the write timeout
+func (b *Bom) DeleteManyWithContext(ctx context.Context) (*mongo.DeleteResult, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	return b.Mongo().DeleteMany(ctx, b.getCondition(), b.newDeleteOptions())
 }
 
 func (b *Bom) ListWithPagination(callback func(cursor *mongo.Cursor) error) (*Pagination, error) {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	findOptions := options.Find()
+	return b.ListWithPaginationWithContext(context.Background(), callback)
+}
+
+// ListWithPaginationWithContext is ListWithPagination bounded by ctx as well as the read timeout,
+// the count and the find share the same deadline
+func (b *Bom) ListWithPaginationWithContext(ctx context.Context, callback func(cursor *mongo.Cursor) error) (pagination *Pagination, err error) {
+	if err := b.Err(); err != nil {
+		return &Pagination{}, err
+	}
 	limit, offset := b.calculateOffset(b.limit.Page, b.limit.Size)
+	if err := b.checkMaxScan(ctx, int64(limit), int64(offset)); err != nil {
+		return &Pagination{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	findOptions := b.newFindOptions(ctx)
 	findOptions.SetLimit(int64(limit)).SetSkip(int64(offset))
 	if sm, ok := b.getSort(b.sort); ok {
 		findOptions.SetSort(sm)
@@ -623,39 +2024,70 @@ func (b *Bom) ListWithPagination(callback func(cursor *mongo.Cursor) error) (*Pa
 	}
 
 	var count int64
-	var err error
-	if condition != nil {
-		if bs, ok := condition.(primitive.M); ok {
-			if len(bs) > 0 {
-				count, err = b.Mongo().CountDocuments(ctx, condition)
-			} else {
-				count, err = b.Mongo().EstimatedDocumentCount(ctx)
-			}
-		}
+	// exact is false when the count comes from the collection metadata, which can undercount
+	exact := true
+	stats := &PaginationStats{}
+	started := time.Now()
+	if bs, ok := condition.(primitive.M); ok && len(bs) == 0 {
+		count, err = b.readQuery().EstimatedDocumentCount(ctx)
+		exact = false
+	} else {
+		count, err = b.readQuery().CountDocuments(ctx, condition, b.newCountOptions())
 	}
+	stats.CountDuration = time.Since(started)
 
 	if err != nil {
 		return &Pagination{}, err
 	}
-	cur, err := b.Mongo().Find(ctx, condition, findOptions)
+	// the requested page is past the last one, there is nothing to find
+	if exact && int64(offset) >= count {
+		b.truncated = false
+		pagination = b.getPagination(int32(count), b.limit.Page, b.limit.Size)
+		if b.withStats {
+			pagination.Stats = stats
+		}
+		return pagination, nil
+	}
+	started = time.Now()
+	cur, err := b.readQuery().Find(ctx, condition, findOptions)
 	if err != nil {
 		return &Pagination{}, err
 	}
-	defer cur.Close(ctx)
+	defer closeCursor(ctx, cur, &err)
+	b.truncated = false
+	var processed int
 	for cur.Next(ctx) {
+		if b.maxResults > 0 && processed >= b.maxResults {
+			b.truncated = true
+			break
+		}
 		err = callback(cur)
+		processed++
 	}
 	if err := cur.Err(); err != nil {
 		return &Pagination{}, err
 	}
-	pagination := b.getPagination(int32(count), b.limit.Page, b.limit.Size)
+	stats.FindDuration = time.Since(started)
+	pagination = b.getPagination(int32(count), b.limit.Page, b.limit.Size)
+	if b.withStats {
+		pagination.Stats = stats
+	}
 	return pagination, err
 }
 
 func (b *Bom) ListWithLastId(callback func(cursor *mongo.Cursor) error) (lastId string, err error) {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
+	return b.ListWithLastIdWithContext(context.Background(), callback)
+}
+
+// ListWithLastIdWithContext is ListWithLastId bounded by ctx as well as the read timeout
+func (b *Bom) ListWithLastIdWithContext(ctx context.Context, callback func(cursor *mongo.Cursor) error) (lastId string, err error) {
+	if err := b.Err(); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
 	lastId = b.lastId
-	findOptions := options.Find()
+	findOptions := b.newFindOptions(ctx)
 	findOptions.SetLimit(int64(b.limit.Size))
 	cur := &mongo.Cursor{}
 	if projection, ok := b.buildProjection(); ok {
@@ -665,11 +2097,11 @@ func (b *Bom) ListWithLastId(callback func(cursor *mongo.Cursor) error) (lastId
 	if lastId != "" {
 		b.WhereConditions("_id", ">", ToObj(lastId))
 	}
-	cur, err = b.Mongo().Find(ctx, b.getCondition(), findOptions)
+	cur, err = b.readQuery().Find(ctx, b.getCondition(), findOptions)
 	if err != nil {
 		return "", err
 	}
-	defer cur.Close(ctx)
+	defer closeCursor(ctx, cur, &err)
 
 	var lastElement primitive.ObjectID
 	for cur.Next(ctx) {
@@ -680,7 +2112,7 @@ func (b *Bom) ListWithLastId(callback func(cursor *mongo.Cursor) error) (lastId
 		return "", err
 	}
 
-	count, err := b.Mongo().CountDocuments(ctx, b.getCondition())
+	count, err := b.readQuery().CountDocuments(ctx, b.getCondition(), b.newCountOptions())
 	if err != nil {
 		return "", err
 	}
@@ -693,22 +2125,114 @@ func (b *Bom) ListWithLastId(callback func(cursor *mongo.Cursor) error) (lastId
 }
 
 func (b *Bom) List(callback func(cursor *mongo.Cursor) error) error {
-	ctx, _ := context.WithTimeout(context.Background(), DefaultQueryTimeout)
-	findOptions := options.Find()
+	return b.ListWithContext(context.Background(), callback)
+}
+
+// ListWithContext is List bounded by ctx as well as the read timeout,
+// a cancelled ctx also stops InWhereChunked before its next chunk
+func (b *Bom) ListWithContext(ctx context.Context, callback func(cursor *mongo.Cursor) error) error {
+	if err := b.Err(); err != nil {
+		return err
+	}
+	if err := b.checkMaxScan(ctx, int64(b.maxResults), 0); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	findOptions := b.newFindOptions(ctx)
 	if projection, ok := b.buildProjection(); ok {
 		findOptions.SetProjection(projection)
 	}
 
-	cur, err := b.Mongo().Find(ctx, b.getCondition(), findOptions)
-	if err != nil {
+	b.truncated = false
+	var processed int
+	seen := make(map[string]bool)
+	return b.eachInChunk(func() (err error) {
+		cur, err := b.readQuery().Find(ctx, b.getCondition(), findOptions)
+		if err != nil {
+			return b.partialError(processed, err)
+		}
+		defer closeCursor(ctx, cur, &err)
+		for cur.Next(ctx) {
+			if b.inChunks != nil && seenID(seen, cur.Current) {
+				continue
+			}
+			if b.maxResults > 0 && processed >= b.maxResults {
+				b.truncated = true
+				break
+			}
+			err = callback(cur)
+			processed++
+		}
+		if err := cur.Err(); err != nil {
+			return b.partialError(processed, err)
+		}
 		return err
+	})
+}
+
+// Cursor runs the find built from the current conditions, projection and sort and returns the live cursor.
+// The caller owns the cursor: iterate it with its own context and always Close it when done.
+func (b *Bom) Cursor() (*mongo.Cursor, error) {
+	return b.CursorWithContext(context.Background())
+}
+
+// CursorWithContext is Cursor with the find bounded by ctx as well as the read timeout
+func (b *Bom) CursorWithContext(ctx context.Context) (*mongo.Cursor, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
 	}
-	defer cur.Close(ctx)
-	for cur.Next(ctx) {
-		err = callback(cur)
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	findOptions := b.newFindOptions(ctx)
+	if projection, ok := b.buildProjection(); ok {
+		findOptions.SetProjection(projection)
 	}
-	if err := cur.Err(); err != nil {
-		return err
+	if sm, ok := b.getSort(b.sort); ok {
+		findOptions.SetSort(sm)
+	}
+	if b.maxResults > 0 {
+		findOptions.SetLimit(int64(b.maxResults))
+	}
+	return b.readQuery().Find(ctx, b.getCondition(), findOptions)
+}
+
+// closeCursor closes cur and reports its error through err unless an earlier one is already there
+func closeCursor(ctx context.Context, cur *mongo.Cursor, err *error) {
+	if closeErr := cur.Close(ctx); closeErr != nil && *err == nil {
+		*err = closeErr
+	}
+}
+
+func (b *Bom) decodeMap(raw bson.Raw) (bson.M, error) {
+	var doc bson.M
+	if err := b.unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if b.hexObjectIDs {
+		HexObjectIDs(doc)
 	}
-	return err
-}
\ No newline at end of file
+	return doc, nil
+}
+
+// ListMaps decodes every matching document into a bson.M
+func (b *Bom) ListMaps() ([]bson.M, error) {
+	return b.ListMapsWithContext(context.Background())
+}
+
+// ListMapsWithContext is ListMaps bounded by ctx as well as the read timeout
+func (b *Bom) ListMapsWithContext(ctx context.Context) ([]bson.M, error) {
+	raws, err := b.rawDocuments(ctx, "maps", 0)
+	if err != nil && !isPartialResults(err) {
+		return nil, err
+	}
+	var docs []bson.M
+	for _, raw := range raws {
+		doc, err := b.decodeMap(raw)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, err
+}
@@ -0,0 +1,851 @@
+package bom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTestBom returns a builder on a client that never connects, for tests of the query building only
+func newTestBom(t *testing.T, opts ...Option) *Bom {
+	t.Helper()
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(append([]Option{SetMongoClient(client), SetDatabaseName("bom_test"), SetCollection("docs")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// newUnreachableBom returns a builder on a connected client to an unreachable address,
+// every operation waits on server selection until its context is done
+func newUnreachableBom(t *testing.T, opts ...Option) *Bom {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return newTestBom(t, append([]Option{SetMongoClient(client)}, opts...)...)
+}
+
+// newMongoBom returns a builder on a fresh collection of the server in BOM_TEST_MONGO_URI,
+// the test is skipped when the variable is not set
+func newMongoBom(t *testing.T, opts ...Option) *Bom {
+	t.Helper()
+	uri := os.Getenv("BOM_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("BOM_TEST_MONGO_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := fmt.Sprintf("%s_%d", t.Name(), time.Now().UnixNano())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = client.Database("bom_test").Collection(collection).Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	b, err := New(append([]Option{SetMongoClient(client), SetDatabaseName("bom_test"), SetCollection(collection)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func assertFilter(t *testing.T, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filter = %#v, want %#v", got, want)
+	}
+}
+
+// newMongoBomOn returns a builder without conditions on the collection of b
+func newMongoBomOn(t *testing.T, b *Bom) *Bom {
+	t.Helper()
+	other, err := New(SetMongoClient(b.client), SetDatabaseName(b.dbName), SetCollection(b.dbCollection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return other
+}
+
+// seed inserts docs into the builder's collection
+func seed(t *testing.T, b *Bom, docs ...interface{}) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := b.Mongo().InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListWithPaginationRawCondition(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"n": 1}, bson.M{"n": 2}, bson.M{"n": 3})
+
+	var found int
+	pagination, err := b.WithCondition(bson.D{{Key: "n", Value: bson.D{{Key: "$gte", Value: 1}}}}).
+		WithLimit(&Limit{Page: 2, Size: 2}).
+		ListWithPagination(func(cur *mongo.Cursor) error {
+			found++
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 1 {
+		t.Errorf("found %d documents on page 2, want 1", found)
+	}
+	if pagination.TotalCount != 3 {
+		t.Errorf("TotalCount = %d, want 3", pagination.TotalCount)
+	}
+}
+
+func TestSingleResultRefusesOnErr(t *testing.T) {
+	b := newTestBom(t, SetAllowedFields("name")).Where("secret", 1)
+	err := b.FindOneAndDelete().Err()
+	var marshalErr mongo.MarshalError
+	if !errors.As(err, &marshalErr) || !errors.Is(marshalErr.Err, ErrFieldNotQueryable) {
+		t.Fatalf("FindOneAndDelete error = %v, want ErrFieldNotQueryable", err)
+	}
+}
+
+func TestAllowedFieldsFromDSL(t *testing.T) {
+	b, err := newTestBom(t, SetAllowedFields("name")).
+		FromDSL([]byte(`{"or":[{"field":"name","op":"eq","value":"a"},{"not":{"field":"secret","op":"eq","value":1}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Err(); !errors.Is(err, ErrFieldNotQueryable) {
+		t.Fatalf("Err() = %v, want ErrFieldNotQueryable", err)
+	}
+}
+
+func TestWhereArrayIndex(t *testing.T) {
+	b := newTestBom(t).WhereArrayIndex("items", 2, "x")
+	if err := b.Err(); err != nil {
+		t.Fatal(err)
+	}
+	assertFilter(t, b.getCondition(), bson.M{"$and": []bson.M{{"items.2": "x"}}})
+
+	if err := newTestBom(t).WhereArrayIndex("items", -1, "x").Err(); err == nil {
+		t.Error("negative index: Err() = nil, want an error")
+	}
+}
+
+func TestCreatedWithin(t *testing.T) {
+	b := newTestBom(t).CreatedWithin(time.Hour)
+	id := b.whereConditions[0]["value"].(primitive.D)[0].Value.(primitive.ObjectID)
+	if !bytes.Equal(id[4:], make([]byte, 8)) {
+		t.Errorf("boundary %s has non-zero tail bytes", id.Hex())
+	}
+	if d := time.Since(id.Timestamp()); d < time.Hour-time.Second || d > time.Hour+time.Second {
+		t.Errorf("boundary timestamp %s is not an hour ago", id.Timestamp())
+	}
+}
+
+func TestStartsWithCICollation(t *testing.T) {
+	b := newTestBom(t).StartsWithCI("name", "jo")
+	if b.collation == nil || b.collation.Strength != 2 {
+		t.Errorf("collation = %+v, want strength 2", b.collation)
+	}
+
+	fr := &options.Collation{Locale: "fr", Strength: 1}
+	b = newTestBom(t).WithCollation(fr).StartsWithCI("name", "jo")
+	if b.collation != fr || b.Err() != nil {
+		t.Errorf("collation = %+v, err = %v, want the caller's collation kept", b.collation, b.Err())
+	}
+
+	b = newTestBom(t).WithCollation(&options.Collation{Locale: "en", Strength: 3}).StartsWithCI("name", "jo")
+	if b.Err() == nil {
+		t.Error("case-sensitive collation: Err() = nil, want an error")
+	}
+}
+
+func TestWithContextVariantsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	noop := func(*mongo.Cursor) error { return nil }
+	calls := map[string]func(b *Bom) error{
+		"ListAggregate": func(b *Bom) error { return b.ListAggregateWithContext(ctx, noop) },
+		"Aggregate":     func(b *Bom) error { return b.AggregateWithContext(ctx, nil, noop) },
+		"AggregateWithPagination": func(b *Bom) error {
+			_, err := b.AggregateWithPaginationWithContext(ctx, noop)
+			return err
+		},
+		"Distinct": func(b *Bom) error {
+			_, err := b.DistinctWithContext(ctx, "a")
+			return err
+		},
+		"DistinctCombo": func(b *Bom) error {
+			_, err := b.DistinctComboWithContext(ctx, "a", "b")
+			return err
+		},
+		"DistinctCI": func(b *Bom) error {
+			_, err := b.DistinctCIWithContext(ctx, "a")
+			return err
+		},
+		"TopN": func(b *Bom) error {
+			_, err := b.TopNWithContext(ctx, "a", 1)
+			return err
+		},
+		"All": func(b *Bom) error {
+			var docs []bson.M
+			return b.AllWithContext(ctx, &docs)
+		},
+		"One": func(b *Bom) error {
+			var doc bson.M
+			return b.OneWithContext(ctx, &doc)
+		},
+		"ListMaps": func(b *Bom) error {
+			_, err := b.ListMapsWithContext(ctx)
+			return err
+		},
+		"InsertIfAbsent": func(b *Bom) error {
+			_, err := b.Where("a", 1).InsertIfAbsentWithContext(ctx, bson.M{"a": 1})
+			return err
+		},
+		"FindOrCreate": func(b *Bom) error {
+			var doc bson.M
+			_, err := b.Where("a", 1).FindOrCreateWithContext(ctx, bson.M{"a": 1}, &doc)
+			return err
+		},
+		"UpdateOneWithDiff": func(b *Bom) error {
+			_, _, err := b.UpdateOneWithDiffWithContext(ctx, bson.M{"$set": bson.M{"a": 2}})
+			return err
+		},
+		"EstimatedCount": func(b *Bom) error {
+			_, err := b.EstimatedCountWithContext(ctx)
+			return err
+		},
+		"MigrateField": func(b *Bom) error {
+			_, err := b.MigrateFieldWithContext(ctx, "a", "b", func(old interface{}) interface{} { return old }, 10)
+			return err
+		},
+		"InsertOneReturning": func(b *Bom) error {
+			var doc bson.M
+			return b.InsertOneReturningWithContext(ctx, bson.M{"a": 1}, &doc)
+		},
+		"FindOneAndUpdateGuarded": func(b *Bom) error {
+			return b.FindOneAndUpdateGuardedWithContext(ctx, func(g *Group) { g.WhereGt("n", 0) }, bson.M{"$inc": bson.M{"n": -1}}).Err()
+		},
+		"FindExactlyOne": func(b *Bom) error {
+			var doc bson.M
+			return b.FindExactlyOneWithContext(ctx, &doc)
+		},
+		"Preview": func(b *Bom) error {
+			_, _, err := b.PreviewWithContext(ctx, 5)
+			return err
+		},
+		"AnalyzeQuery": func(b *Bom) error {
+			_, err := b.AnalyzeQueryWithContext(ctx)
+			return err
+		},
+		"PageIter": func(b *Bom) error {
+			_, _, err := b.PageIterWithContext(ctx)
+			return err
+		},
+		"AggregateAll": func(b *Bom) error {
+			_, err := AggregateAllWithContext[bson.M](ctx, b, nil)
+			return err
+		},
+		"CreateTimeSeriesCollection": func(b *Bom) error {
+			return b.CreateTimeSeriesCollectionWithContext(ctx, "at", "", "")
+		},
+		"ClusterTime": func(b *Bom) error {
+			_, err := b.ClusterTimeWithContext(ctx)
+			return err
+		},
+		"Watch": func(b *Bom) error {
+			_, err := b.WatchWithContext(ctx, nil)
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(newUnreachableBom(t)); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: err = %v, want context.Canceled", name, err)
+		}
+	}
+}
+
+func TestNorWhere(t *testing.T) {
+	b := newTestBom(t).NorWhere("a", 1).NorWhere("b", 2)
+	assertFilter(t, b.buildCondition(), bson.M{"$nor": []bson.M{{"a": 1}, {"b": 2}}})
+}
+
+func TestWhereNotElemMatch(t *testing.T) {
+	failed := func(g *Group) { g.WhereEq("status", "failed") }
+	b := newTestBom(t).WhereNotElemMatch("items", failed)
+	assertFilter(t, b.buildCondition(), bson.M{"$and": []bson.M{
+		{"items": bson.M{"$not": bson.M{"$elemMatch": bson.M{"status": "failed"}}}},
+	}})
+}
+
+func TestWhereNotElemMatchDiffersFromNegatedElemMatch(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b,
+		bson.M{"name": "mixed", "items": bson.A{bson.M{"status": "ok"}, bson.M{"status": "failed"}}},
+		bson.M{"name": "clean", "items": bson.A{bson.M{"status": "ok"}}},
+	)
+
+	none, err := b.WhereNotElemMatch("items", func(g *Group) { g.WhereEq("status", "failed") }).ListMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none) != 1 || none[0]["name"] != "clean" {
+		t.Errorf("WhereNotElemMatch = %v, want only clean", none)
+	}
+
+	b = newMongoBom(t)
+	seed(t, b,
+		bson.M{"name": "mixed", "items": bson.A{bson.M{"status": "ok"}, bson.M{"status": "failed"}}},
+		bson.M{"name": "clean", "items": bson.A{bson.M{"status": "ok"}}},
+	)
+	some, err := b.WhereElemMatch("items", func(g *Group) { g.WhereNotEq("status", "failed") }).ListMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(some) != 2 {
+		t.Errorf("WhereElemMatch with a negated condition = %v, want both documents", some)
+	}
+}
+
+func TestInWhereSameFieldMerged(t *testing.T) {
+	b := newTestBom(t).InWhere("tag", "go").InWhere("tag", []string{"mongo", "db"})
+	assertFilter(t, b.buildCondition(), bson.M{"tag": bson.M{"$in": []interface{}{"go", "mongo", "db"}}})
+}
+
+func TestQueryTimeoutApplied(t *testing.T) {
+	b := newUnreachableBom(t, SetQueryTimeout(time.Millisecond))
+	if got := b.getReadTimeout(); got != time.Millisecond {
+		t.Errorf("read timeout = %s, want 1ms", got)
+	}
+	if got := b.getWriteTimeout(); got != time.Millisecond {
+		t.Errorf("write timeout = %s, want 1ms", got)
+	}
+	if _, err := b.Count(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Count with a 1ms timeout: err = %v, want context.DeadlineExceeded", err)
+	}
+
+	b = newTestBom(t, SetTimeouts(2*time.Second, 0))
+	if got := b.getReadTimeout(); got != 2*time.Second {
+		t.Errorf("read timeout = %s, want 2s", got)
+	}
+	if got := b.getWriteTimeout(); got != DefaultQueryTimeout {
+		t.Errorf("write timeout = %s, want the query timeout", got)
+	}
+}
+
+func TestTimeoutContextsCanceled(t *testing.T) {
+	var used []context.Context
+	capture := SetRequestIDFunc(func(ctx context.Context) string {
+		used = append(used, ctx)
+		return ""
+	})
+	noop := func(*mongo.Cursor) error { return nil }
+	_ = newTestBom(t, capture).List(noop)
+	_, _ = newTestBom(t, capture).ListWithPagination(noop)
+	_, _ = newTestBom(t, capture).ListWithLastId(noop)
+	_ = newTestBom(t, capture).FindOne(func(*mongo.SingleResult) error { return nil })
+	_, _ = newTestBom(t, capture).ListMaps()
+	if len(used) != 5 {
+		t.Fatalf("captured %d contexts, want 5", len(used))
+	}
+	for i, ctx := range used {
+		if ctx.Err() != context.Canceled {
+			t.Errorf("context %d: err = %v after the call returned, want context.Canceled", i, ctx.Err())
+		}
+	}
+}
+
+func TestNot(t *testing.T) {
+	assertFilter(t, newTestBom(t).Not("status", "deleted").buildCondition(),
+		bson.M{"status": bson.M{"$ne": "deleted"}})
+	assertFilter(t, newTestBom(t).Not("status", "deleted").Not("status", "banned").buildCondition(),
+		bson.M{"status": bson.M{"$nin": []interface{}{"deleted", "banned"}}})
+}
+
+func TestGetSortByFieldName(t *testing.T) {
+	b := newTestBom(t).WithSort(&Sort{Field: "created_at", Type: "desc"})
+	got, ok := b.getSort(b.sort)
+	want := bson.D{{Key: "created_at", Value: int32(-1)}}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("sort = %v, want %v", got, want)
+	}
+}
+
+func TestDeleteOneAndMany(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"kind": "a"}, bson.M{"kind": "a"}, bson.M{"kind": "a"}, bson.M{"kind": "b"})
+
+	res, err := b.Where("kind", "a").DeleteOne()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DeletedCount != 1 {
+		t.Errorf("DeleteOne deleted %d, want 1", res.DeletedCount)
+	}
+
+	b = newMongoBom(t)
+	seed(t, b, bson.M{"kind": "a"}, bson.M{"kind": "a"}, bson.M{"kind": "a"}, bson.M{"kind": "b"})
+	res, err = b.Where("kind", "a").DeleteMany()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DeletedCount != 3 {
+		t.Errorf("DeleteMany deleted %d, want 3", res.DeletedCount)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		condition func(b *Bom) *Bom
+		survivors []string
+	}{
+		{
+			name:      "InWhere",
+			condition: func(b *Bom) *Bom { return b.InWhere("kind", []string{"a", "b"}) },
+			survivors: []string{"c"},
+		},
+		{
+			name:      "OrWhere",
+			condition: func(b *Bom) *Bom { return b.OrWhere("kind", "a").OrWhere("kind", "c") },
+			survivors: []string{"b"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newMongoBom(t)
+			seed(t, b, bson.M{"kind": "a"}, bson.M{"kind": "a"}, bson.M{"kind": "b"}, bson.M{"kind": "c"})
+			if _, err := tc.condition(b).DeleteMany(); err != nil {
+				t.Fatal(err)
+			}
+			docs, err := newMongoBomOn(t, b).ListMaps()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kinds []string
+			for _, doc := range docs {
+				kinds = append(kinds, doc["kind"].(string))
+			}
+			if !reflect.DeepEqual(kinds, tc.survivors) {
+				t.Errorf("survivors = %v, want %v", kinds, tc.survivors)
+			}
+		})
+	}
+}
+
+func TestUpdateManyInWhere(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"tag": "go"}, bson.M{"tag": "mongo"}, bson.M{"tag": "rust"})
+
+	res, err := b.InWhere("tag", []string{"go", "mongo"}).UpdateMany(bson.M{"$set": bson.M{"seen": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ModifiedCount != 2 {
+		t.Errorf("UpdateMany modified %d, want 2", res.ModifiedCount)
+	}
+}
+
+func TestCount(t *testing.T) {
+	b := newMongoBom(t)
+	n, err := b.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("Count on an empty collection = %d, want 0", n)
+	}
+
+	seed(t, b, bson.M{"n": 1}, bson.M{"n": 2}, bson.M{"n": 3})
+	n, err = b.WhereGte("n", 2).Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("filtered Count = %d, want 2", n)
+	}
+}
+
+func TestRangeOperatorsMerged(t *testing.T) {
+	b := newTestBom(t).Gte("age", 18).Lt("age", 65)
+	assertFilter(t, b.buildCondition(), bson.M{"age": bson.M{"$gte": 18, "$lt": 65}})
+
+	b = newTestBom(t).Gt("score", 1).Lte("score", 9)
+	assertFilter(t, b.buildCondition(), bson.M{"score": bson.M{"$gt": 1, "$lte": 9}})
+}
+
+func TestNotInMergedWithIn(t *testing.T) {
+	b := newTestBom(t).InWhere("tag", []string{"go", "mongo"}).NotIn("tag", []string{"mongo"})
+	assertFilter(t, b.buildCondition(), bson.M{"tag": bson.M{"$in": []string{"go", "mongo"}, "$nin": []string{"mongo"}}})
+}
+
+func TestLike(t *testing.T) {
+	b := newTestBom(t).Like("name", "^john").LikeCase("city", "^Par")
+	assertFilter(t, b.buildCondition(), bson.M{"$and": []bson.M{
+		{"name": primitive.Regex{Pattern: "^john", Options: "i"}},
+		{"city": primitive.Regex{Pattern: "^Par"}},
+	}})
+}
+
+func TestExists(t *testing.T) {
+	b := newTestBom(t).Exists("email", true).Exists("deletedAt", false)
+	assertFilter(t, b.buildCondition(), bson.M{"$and": []bson.M{
+		{"email": bson.M{"$exists": true}},
+		{"deletedAt": bson.M{"$exists": false}},
+	}})
+}
+
+func TestOrGroup(t *testing.T) {
+	b := newTestBom(t).OrGroup(func(g *Group) { g.WhereEq("a", 1).WhereEq("b", 2) }).OrWhereEq("c", 3)
+	assertFilter(t, b.buildCondition(), bson.M{"$or": []bson.M{{"a": 1, "b": 2}, {"c": 3}}})
+}
+
+func TestAddSortKeepsInsertionOrder(t *testing.T) {
+	b := newTestBom(t).AddSort("priority", "desc").AddSort("name", "asc")
+	got, _ := b.getSort(b.sort)
+	want := bson.D{{Key: "priority", Value: int32(-1)}, {Key: "name", Value: int32(1)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sort = %v, want %v", got, want)
+	}
+}
+
+func TestSortSerializedInOrder(t *testing.T) {
+	b := newTestBom(t).AddSort("c", "asc").AddSort("a", "desc").AddSort("b", "asc")
+	sort, _ := b.getSort(b.sort)
+	raw, err := bson.Marshal(sort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elems, err := bson.Raw(raw).Elements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, elem := range elems {
+		keys = append(keys, elem.Key())
+	}
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("serialized sort keys = %v, want %v", keys, want)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, option := range map[string]Option{
+		"empty database":   SetDatabaseName(""),
+		"empty collection": SetCollection(""),
+		"zero timeout":     SetQueryTimeout(0),
+		"negative timeout": SetQueryTimeout(-time.Second),
+	} {
+		if _, err := New(SetMongoClient(client), option); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%s: err = %v, want ErrInvalidOption", name, err)
+		}
+	}
+}
+
+func TestGetConditionCombinesRawAndFluent(t *testing.T) {
+	rawM := bson.M{"status": "active"}
+	rawD := bson.D{{Key: "status", Value: "active"}}
+
+	assertFilter(t, newTestBom(t).WithCondition(rawM).getCondition(), rawM)
+	assertFilter(t, newTestBom(t).WithCondition(rawD).getCondition(), rawD)
+
+	assertFilter(t, newTestBom(t).WithCondition(rawM).Where("age", 30).getCondition(),
+		bson.M{"$and": []interface{}{rawM, bson.M{"$and": []bson.M{{"age": 30}}}}})
+	assertFilter(t, newTestBom(t).WithCondition(rawD).Where("age", 30).getCondition(),
+		bson.M{"$and": []interface{}{rawD, bson.M{"$and": []bson.M{{"age": 30}}}}})
+
+	assertFilter(t, newTestBom(t).Where("age", 30).getCondition(), bson.M{"$and": []bson.M{{"age": 30}}})
+}
+
+func TestToObjE(t *testing.T) {
+	id := primitive.NewObjectID()
+	if got, err := ToObjE(id.Hex()); err != nil || got != id {
+		t.Errorf("ToObjE(%s) = %s, %v", id.Hex(), got.Hex(), err)
+	}
+	if _, err := ToObjE("not-an-id"); err == nil {
+		t.Error("ToObjE of a malformed id: err = nil")
+	}
+
+	ids, err := ToObjectsE([]string{id.Hex(), id.Hex()})
+	if err != nil || len(ids) != 2 {
+		t.Errorf("ToObjectsE = %v, %v, want two ids", ids, err)
+	}
+	if _, err := ToObjectsE([]string{id.Hex(), "zz"}); err == nil {
+		t.Error("ToObjectsE with a malformed id: err = nil")
+	}
+}
+
+func TestFindOneAndUpdateReturnAfter(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"name": "counter", "n": 1})
+
+	var before, after bson.M
+	if err := b.Where("name", "counter").FindOneAndUpdate(bson.M{"$inc": bson.M{"n": 1}}).Decode(&before); err != nil {
+		t.Fatal(err)
+	}
+	if before["n"] != int32(1) {
+		t.Errorf("FindOneAndUpdate returned n = %v, want the value before the update 1", before["n"])
+	}
+	if err := b.FindOneAndUpdate(bson.M{"$inc": bson.M{"n": 1}}, ReturnAfter()).Decode(&after); err != nil {
+		t.Fatal(err)
+	}
+	if after["n"] != int32(3) {
+		t.Errorf("FindOneAndUpdate with ReturnAfter returned n = %v, want 3", after["n"])
+	}
+}
+
+func TestFindOneAndReplace(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"name": "old", "extra": true})
+
+	var returned bson.M
+	if err := b.Where("name", "old").FindOneAndReplace(bson.M{"name": "new"}).Decode(&returned); err != nil {
+		t.Fatal(err)
+	}
+	if returned["name"] != "old" {
+		t.Errorf("FindOneAndReplace returned %v, want the old document", returned)
+	}
+
+	stored, err := newMongoBomOn(t, b).ListMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0]["name"] != "new" || stored[0]["extra"] != nil {
+		t.Errorf("stored %v, want only the replacement", stored)
+	}
+}
+
+func TestReplaceOneDropsMissingFields(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"name": "a", "extra": 1})
+
+	res, err := b.Where("name", "a").ReplaceOne(bson.M{"name": "a", "version": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ModifiedCount != 1 {
+		t.Errorf("ReplaceOne modified %d, want 1", res.ModifiedCount)
+	}
+	stored, err := newMongoBomOn(t, b).ListMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0]["version"] != int32(2) {
+		t.Fatalf("stored %v, want the replacement", stored)
+	}
+	if _, ok := stored[0]["extra"]; ok {
+		t.Errorf("stored %v still has the field missing from the replacement", stored[0])
+	}
+}
+
+func TestNotGroup(t *testing.T) {
+	b := newTestBom(t).NotGroup(func(g *Group) { g.WhereEq("status", "banned").WhereGt("strikes", 3) })
+	assertFilter(t, b.buildCondition(), bson.M{"$nor": []bson.M{
+		{"$and": []bson.M{{"status": "banned"}, {"strikes": primitive.D{{Key: "$gt", Value: 3}}}}},
+	}})
+}
+
+func TestWithUpsert(t *testing.T) {
+	b := newMongoBom(t)
+	res, err := b.Where("name", "missing").WithUpsert().UpdateRaw(bson.M{"$set": bson.M{"n": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.UpsertedID == nil {
+		t.Error("UpdateRaw with WithUpsert: UpsertedID = nil, want the inserted id")
+	}
+
+	res, err = newMongoBom(t).Where("name", "missing").WithUpsert().UpdateMany(bson.M{"$set": bson.M{"n": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.UpsertedID == nil {
+		t.Error("UpdateMany with WithUpsert: UpsertedID = nil, want the inserted id")
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"tag": "go"}, bson.M{"tag": "go"}, bson.M{"tag": "mongo"}, bson.M{"tag": "rust", "hidden": true})
+
+	values, err := b.Exists("hidden", false).Distinct("tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 {
+		t.Errorf("Distinct = %v, want go and mongo", values)
+	}
+}
+
+func TestExcludeProjection(t *testing.T) {
+	projection, ok := newTestBom(t).Exclude("passwordHash", "token").buildProjection()
+	if !ok {
+		t.Fatal("Exclude built no projection")
+	}
+	assertFilter(t, projection, bson.M{"passwordHash": 0, "token": 0})
+
+	b := newTestBom(t).Select("name").Exclude("_id")
+	if err := b.Err(); err != nil {
+		t.Fatalf("Select with Exclude of _id: Err() = %v, want nil", err)
+	}
+	projection, _ = b.buildProjection()
+	assertFilter(t, projection, bson.M{"name": 1, "_id": 0})
+
+	if err := newTestBom(t).Select("name").Exclude("token").Err(); !errors.Is(err, ErrMixedProjection) {
+		t.Errorf("Select with Exclude: Err() = %v, want ErrMixedProjection", err)
+	}
+}
+
+func TestSelectAndExcludeDecoded(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"name": "John", "email": "john@example.com", "secret": "s3cr3t"})
+
+	docs, err := newMongoBomOn(t, b).Select("name").ListMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Select returned %d documents, want 1", len(docs))
+	}
+	for _, field := range []string{"_id", "name"} {
+		if _, ok := docs[0][field]; !ok {
+			t.Errorf("Select(name): %q missing from %v", field, docs[0])
+		}
+	}
+	for _, field := range []string{"email", "secret"} {
+		if _, ok := docs[0][field]; ok {
+			t.Errorf("Select(name): %q present in %v", field, docs[0])
+		}
+	}
+
+	docs, err = newMongoBomOn(t, b).Exclude("secret").ListMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Exclude returned %d documents, want 1", len(docs))
+	}
+	for _, field := range []string{"_id", "name", "email"} {
+		if _, ok := docs[0][field]; !ok {
+			t.Errorf("Exclude(secret): %q missing from %v", field, docs[0])
+		}
+	}
+	if _, ok := docs[0]["secret"]; ok {
+		t.Errorf("Exclude(secret): secret present in %v", docs[0])
+	}
+}
+
+func TestGetTotalPages(t *testing.T) {
+	for _, tc := range []struct {
+		total, size, want int32
+	}{
+		{total: 25, size: 10, want: 3},
+		{total: 20, size: 10, want: 2},
+		{total: 0, size: 10, want: 0},
+		{total: 25, size: 0, want: 2},
+	} {
+		b := newTestBom(t)
+		b.pagination.TotalCount = tc.total
+		b.pagination.Size = tc.size
+		if got := b.getTotalPages(); got != tc.want {
+			t.Errorf("getTotalPages(%d docs, size %d) = %d, want %d", tc.total, tc.size, got, tc.want)
+		}
+	}
+}
+
+func TestCollationOnWrites(t *testing.T) {
+	b := newTestBom(t).StartsWithCI("name", "jo")
+	want := b.collation
+	hasCollation := func(name string, got *options.Collation) {
+		t.Helper()
+		if got != want {
+			t.Errorf("%s collation = %+v, want %+v", name, got, want)
+		}
+	}
+	update := options.MergeUpdateOptions(b.getUpdateOptions()...)
+	hasCollation("update", update.Collation)
+	hasCollation("delete", b.newDeleteOptions().Collation)
+	hasCollation("findOneAndUpdate", options.MergeFindOneAndUpdateOptions(b.getFindOneAndUpdateOptions()...).Collation)
+	hasCollation("findOneAndReplace", options.MergeFindOneAndReplaceOptions(b.getFindOneAndReplaceOptions()...).Collation)
+}
+
+func TestStartsWithCIDeleteMany(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"name": "John"}, bson.M{"name": "joan"}, bson.M{"name": "Mary"})
+
+	res, err := b.StartsWithCI("name", "jo").DeleteMany()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DeletedCount != 2 {
+		t.Errorf("DeleteMany deleted %d, want the 2 names starting with jo in any case", res.DeletedCount)
+	}
+}
+
+func TestMaxScanBoundedByLimit(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"n": 1}, bson.M{"n": 2}, bson.M{"n": 3}, bson.M{"n": 4}, bson.M{"n": 5})
+
+	var doc bson.M
+	if err := newMongoBomOn(t, b).WithMaxScan(2).One(&doc); err != nil {
+		t.Errorf("One with max scan 2: err = %v, want nil", err)
+	}
+	if _, err := newMongoBomOn(t, b).WithMaxScan(2).WithLimit(&Limit{Page: 1, Size: 2}).ListWithPagination(func(*mongo.Cursor) error { return nil }); err != nil {
+		t.Errorf("first page of 2 with max scan 2: err = %v, want nil", err)
+	}
+	err := newMongoBomOn(t, b).WithMaxScan(2).List(func(*mongo.Cursor) error { return nil })
+	if !errors.Is(err, ErrMaxScanExceeded) {
+		t.Errorf("unbounded List with max scan 2: err = %v, want ErrMaxScanExceeded", err)
+	}
+}
+
+func TestMaxScanUsesCallerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := newTestBom(t).WithMaxScan(10).ListWithContext(ctx, func(*mongo.Cursor) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled from the explain", err)
+	}
+}
+
+func TestExcludeKeepsDefaultProjection(t *testing.T) {
+	hidden := bson.M{"passwordHash": 0}
+
+	projection, _ := newTestBom(t, SetDefaultProjection(hidden)).ExcludeIf(true, "token").buildProjection()
+	assertFilter(t, projection, primitive.M{"passwordHash": 0, "token": 0})
+
+	projection, _ = newTestBom(t, SetDefaultProjection(hidden)).WithProjection(bson.M{"token": 0}).buildProjection()
+	assertFilter(t, projection, primitive.M{"passwordHash": 0, "token": 0})
+
+	projection, _ = newTestBom(t, SetDefaultProjection(hidden)).Exclude("_id").buildProjection()
+	assertFilter(t, projection, primitive.M{"passwordHash": 0, "_id": 0})
+
+	projection, _ = newTestBom(t, SetDefaultProjection(hidden)).Select("name").buildProjection()
+	assertFilter(t, projection, primitive.M{"name": 1})
+
+	visible := bson.D{{Key: "name", Value: 1}, {Key: "email", Value: 1}}
+	projection, _ = newTestBom(t, SetDefaultProjection(visible)).Exclude("email").buildProjection()
+	assertFilter(t, projection, primitive.M{"name": int32(1)})
+}
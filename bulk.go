@@ -0,0 +1,53 @@
+package bom
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BulkWriteBatched runs models as successive bulk writes of batchSize, each with its own write timeout,
+// and calls onProgress (may be nil) with the number of models written so far after every batch.
+// The result sums the counts of the batches and UpsertedIDs are keyed by the index in models.
+func (b *Bom) BulkWriteBatched(models []mongo.WriteModel, batchSize int, onProgress func(done, total int)) (*mongo.BulkWriteResult, error) {
+	return b.BulkWriteBatchedWithContext(context.Background(), models, batchSize, onProgress)
+}
+
+// BulkWriteBatchedWithContext is BulkWriteBatched which stops before the next batch once ctx is done,
+// returning the result of the batches already written together with ctx.Err().
+// On a write error the indexes of the BulkWriteException are relative to the failed batch.
+func (b *Bom) BulkWriteBatchedWithContext(ctx context.Context, models []mongo.WriteModel, batchSize int, onProgress func(done, total int)) (*mongo.BulkWriteResult, error) {
+	if batchSize < 1 {
+		batchSize = DefaultSize
+	}
+	total := &mongo.BulkWriteResult{UpsertedIDs: make(map[int64]interface{})}
+	for start := 0; start < len(models); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		end := start + batchSize
+		if end > len(models) {
+			end = len(models)
+		}
+		batchCtx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+		res, err := b.Mongo().BulkWrite(batchCtx, models[start:end], b.newBulkWriteOptions())
+		cancel()
+		if res != nil {
+			total.InsertedCount += res.InsertedCount
+			total.MatchedCount += res.MatchedCount
+			total.ModifiedCount += res.ModifiedCount
+			total.DeletedCount += res.DeletedCount
+			total.UpsertedCount += res.UpsertedCount
+			for i, id := range res.UpsertedIDs {
+				total.UpsertedIDs[int64(start)+i] = id
+			}
+		}
+		if err != nil {
+			return total, err
+		}
+		if onProgress != nil {
+			onProgress(end, len(models))
+		}
+	}
+	return total, nil
+}
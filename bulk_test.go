@@ -0,0 +1,23 @@
+package bom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestBulkWriteBatchedWithContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	models := []mongo.WriteModel{mongo.NewInsertOneModel().SetDocument(bson.M{"n": 1})}
+	res, err := newTestBom(t).BulkWriteBatchedWithContext(ctx, models, 10, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if res == nil || res.InsertedCount != 0 {
+		t.Errorf("result = %+v, want an empty result", res)
+	}
+}
@@ -0,0 +1,248 @@
+package bom
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type cacheEntry struct {
+	docs    []bson.Raw
+	expires time.Time
+}
+
+// MaxCacheEntries bounds the WithCache entries kept in process, expired entries are swept first
+// when it is reached and then arbitrary ones are evicted
+const MaxCacheEntries = 1000
+
+var queryCache = struct {
+	sync.Mutex
+	entries map[string]cacheEntry
+}{entries: make(map[string]cacheEntry)}
+
+// cacheGet returns the documents cached under key, deleting the entry once expired
+func cacheGet(key string) ([]bson.Raw, bool) {
+	queryCache.Lock()
+	defer queryCache.Unlock()
+	entry, ok := queryCache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expires) {
+		delete(queryCache.entries, key)
+		return nil, false
+	}
+	return entry.docs, true
+}
+
+func cachePut(key string, docs []bson.Raw, ttl time.Duration) {
+	queryCache.Lock()
+	defer queryCache.Unlock()
+	if _, ok := queryCache.entries[key]; !ok && len(queryCache.entries) >= MaxCacheEntries {
+		now := time.Now()
+		for k, entry := range queryCache.entries {
+			if !now.Before(entry.expires) {
+				delete(queryCache.entries, k)
+			}
+		}
+		for k := range queryCache.entries {
+			if len(queryCache.entries) < MaxCacheEntries {
+				break
+			}
+			delete(queryCache.entries, k)
+		}
+	}
+	queryCache.entries[key] = cacheEntry{docs: docs, expires: time.Now().Add(ttl)}
+}
+
+// WithCache keeps the documents read by All, One and ListMaps in process for ttl, under key or,
+// when key is empty, under a hash of the database, collection, conditions, sort and projection.
+// It is a best-effort cache for hot reference data: entries expire by ttl or are evicted past MaxCacheEntries,
+// writes never invalidate them, and the callback based List and FindOne always go to the database.
+func (b *Bom) WithCache(ttl time.Duration, key string) *Bom {
+	b.cacheTTL = ttl
+	b.cacheKey = key
+	return b
+}
+
+func (b *Bom) getCacheKey(kind string) string {
+	if b.cacheKey != "" {
+		return kind + ":" + b.cacheKey
+	}
+	projection, _ := b.buildProjection()
+	sort, _ := b.getSort(b.sort)
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%v|%v|%v|%d", b.dbName, b.dbCollection, b.getCondition(), sort, projection, b.maxResults)))
+	return kind + ":" + hex.EncodeToString(sum[:])
+}
+
+// rawDocuments reads the matching documents, from the cache when WithCache is on
+func (b *Bom) rawDocuments(ctx context.Context, kind string, limit int64) ([]bson.Raw, error) {
+	if b.cacheTTL <= 0 {
+		return b.fetchRawDocuments(ctx, limit)
+	}
+	key := b.getCacheKey(kind)
+	if docs, ok := cacheGet(key); ok {
+		return docs, nil
+	}
+	docs, err := b.fetchRawDocuments(ctx, limit)
+	if err != nil {
+		return docs, err
+	}
+	cachePut(key, docs, b.cacheTTL)
+	return docs, nil
+}
+
+func (b *Bom) fetchRawDocuments(ctx context.Context, limit int64) ([]bson.Raw, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	if limit == 0 && b.maxResults > 0 {
+		limit = int64(b.maxResults)
+	}
+	if err := b.checkMaxScan(ctx, limit, 0); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	findOptions := b.newFindOptions(ctx)
+	if projection, ok := b.buildProjection(); ok {
+		findOptions.SetProjection(projection)
+	}
+	if sm, ok := b.getSort(b.sort); ok {
+		findOptions.SetSort(sm)
+	}
+	if limit > 0 {
+		findOptions.SetLimit(limit)
+	}
+	var docs []bson.Raw
+	seen := make(map[string]bool)
+	err := b.eachInChunk(func() (err error) {
+		if limit > 0 && int64(len(docs)) >= limit {
+			return nil
+		}
+		cur, err := b.readQuery().Find(ctx, b.getCondition(), findOptions)
+		if err != nil {
+			return b.partialError(len(docs), err)
+		}
+		defer closeCursor(ctx, cur, &err)
+		for cur.Next(ctx) {
+			if b.inChunks != nil && seenID(seen, cur.Current) {
+				continue
+			}
+			if limit > 0 && int64(len(docs)) >= limit {
+				break
+			}
+			docs = append(docs, append(bson.Raw(nil), cur.Current...))
+		}
+		return b.partialError(len(docs), cur.Err())
+	})
+	return docs, err
+}
+
+func (b *Bom) unmarshal(raw bson.Raw, val interface{}) error {
+	if b.registry != nil {
+		return bson.UnmarshalWithRegistry(b.registry, raw, val)
+	}
+	return bson.Unmarshal(raw, val)
+}
+
+// All decodes every matching document into results, which must be a pointer to a slice
+func (b *Bom) All(results interface{}) error {
+	return b.AllWithContext(context.Background(), results)
+}
+
+// AllWithContext is All bounded by ctx as well as the read timeout
+func (b *Bom) AllWithContext(ctx context.Context, results interface{}) error {
+	sliceVal := reflect.ValueOf(results)
+	if sliceVal.Kind() != reflect.Ptr || sliceVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("results argument must be a pointer to a slice, got %T", results)
+	}
+	docs, err := b.rawDocuments(ctx, "all", 0)
+	if err != nil && !isPartialResults(err) {
+		return err
+	}
+	slice := sliceVal.Elem()
+	elemType := slice.Type().Elem()
+	slice = slice.Slice(0, 0)
+	for _, doc := range docs {
+		elem := reflect.New(elemType)
+		if err := b.unmarshal(doc, elem.Interface()); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	sliceVal.Elem().Set(slice)
+	return err
+}
+
+// One decodes the first matching document into result, ErrNotFound when nothing matches
+func (b *Bom) One(result interface{}) error {
+	return b.OneWithContext(context.Background(), result)
+}
+
+// OneWithContext is One bounded by ctx as well as the read timeout
+func (b *Bom) OneWithContext(ctx context.Context, result interface{}) error {
+	docs, err := b.rawDocuments(ctx, "one", 1)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return ErrNotFound
+	}
+	return b.unmarshal(docs[0], result)
+}
+
+// FindExactlyOne decodes the only matching document into out, ErrNotFound when nothing matches
+// and ErrMultipleFound when a second one does, which a plain One would hide by returning the first
+func (b *Bom) FindExactlyOne(out interface{}) error {
+	return b.FindExactlyOneWithContext(context.Background(), out)
+}
+
+// FindExactlyOneWithContext is FindExactlyOne bounded by ctx as well as the read timeout
+func (b *Bom) FindExactlyOneWithContext(ctx context.Context, out interface{}) error {
+	docs, err := b.fetchRawDocuments(ctx, 2)
+	if err != nil {
+		return err
+	}
+	switch len(docs) {
+	case 0:
+		return ErrNotFound
+	case 1:
+		return b.unmarshal(docs[0], out)
+	}
+	return ErrMultipleFound
+}
+
+// Preview returns the first n matching documents and whether there are more, reading only n+1 of them
+func (b *Bom) Preview(n int) (docs []bson.M, hasMore bool, err error) {
+	return b.PreviewWithContext(context.Background(), n)
+}
+
+// PreviewWithContext is Preview bounded by ctx as well as the read timeout
+func (b *Bom) PreviewWithContext(ctx context.Context, n int) (docs []bson.M, hasMore bool, err error) {
+	if n < 1 {
+		return nil, false, fmt.Errorf("preview size must be positive, got %d", n)
+	}
+	raws, err := b.fetchRawDocuments(ctx, int64(n+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(raws) > n {
+		hasMore = true
+		raws = raws[:n]
+	}
+	for _, raw := range raws {
+		doc, err := b.decodeMap(raw)
+		if err != nil {
+			return nil, false, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, hasMore, nil
+}
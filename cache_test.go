@@ -0,0 +1,35 @@
+package bom
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCacheExpiredEntryDeleted(t *testing.T) {
+	cachePut("test:expired", nil, -time.Second)
+	if _, ok := cacheGet("test:expired"); ok {
+		t.Fatal("expired entry returned")
+	}
+	queryCache.Lock()
+	_, ok := queryCache.entries["test:expired"]
+	queryCache.Unlock()
+	if ok {
+		t.Error("expired entry still in the cache after lookup")
+	}
+}
+
+func TestCacheBounded(t *testing.T) {
+	for i := 0; i < MaxCacheEntries+10; i++ {
+		cachePut(fmt.Sprintf("test:bounded:%d", i), nil, time.Minute)
+	}
+	queryCache.Lock()
+	n := len(queryCache.entries)
+	queryCache.Unlock()
+	if n > MaxCacheEntries {
+		t.Errorf("%d cache entries, want at most %d", n, MaxCacheEntries)
+	}
+	if _, ok := cacheGet(fmt.Sprintf("test:bounded:%d", MaxCacheEntries+9)); !ok {
+		t.Error("latest entry missing")
+	}
+}
@@ -0,0 +1,55 @@
+package bom
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inChunks struct {
+	field   string
+	values  []interface{}
+	size    int
+	current []interface{}
+}
+
+func (c *inChunks) condition() primitive.M {
+	values := c.values
+	if c.current != nil {
+		values = c.current
+	}
+	return primitive.M{c.field: primitive.M{"$in": values}}
+}
+
+// eachInChunk calls fn once per InWhereChunked chunk with the condition narrowed to it,
+// or once with the full condition when there are no chunks
+func (b *Bom) eachInChunk(fn func() error) error {
+	if b.inChunks == nil {
+		return fn()
+	}
+	defer func() { b.inChunks.current = nil }()
+	values := b.inChunks.values
+	for start := 0; start < len(values); start += b.inChunks.size {
+		end := start + b.inChunks.size
+		if end > len(values) {
+			end = len(values)
+		}
+		b.inChunks.current = values[start:end]
+		if err := fn(); err != nil {
+			return err
+		}
+		if b.truncated {
+			return nil
+		}
+	}
+	return nil
+}
+
+func seenID(seen map[string]bool, doc bson.Raw) bool {
+	id := doc.Lookup("_id")
+	key := string(id.Type) + string(id.Value)
+	if seen[key] {
+		return true
+	}
+	seen[key] = true
+	return false
+}
@@ -0,0 +1,111 @@
+package bom
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type (
+	// Collection is a typed repository over one collection, every call starts from a fresh Bom
+	// so conditions never leak between calls
+	Collection[T any] struct {
+		options []Option
+	}
+	// Cond adds conditions to the builder of a Collection call
+	Cond func(b *Bom) *Bom
+)
+
+func NewCollection[T any](client *mongo.Client, dbName string, collection string, opts ...Option) (*Collection[T], error) {
+	c := &Collection[T]{
+		options: append([]Option{
+			SetMongoClient(client),
+			SetDatabaseName(dbName),
+			SetCollection(collection),
+		}, opts...),
+	}
+	if _, err := c.Bom(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Bom returns a new builder bound to the collection for anything the typed methods don't cover
+func (c *Collection[T]) Bom() (*Bom, error) {
+	return New(c.options...)
+}
+
+func (c *Collection[T]) with(conds []Cond) (*Bom, error) {
+	b, err := c.Bom()
+	if err != nil {
+		return nil, err
+	}
+	for _, cond := range conds {
+		b = cond(b)
+	}
+	return b, nil
+}
+
+func (c *Collection[T]) Find(conds ...Cond) ([]T, error) {
+	b, err := c.with(conds)
+	if err != nil {
+		return nil, err
+	}
+	var result []T
+	err = b.List(func(cursor *mongo.Cursor) error {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return err
+		}
+		result = append(result, item)
+		return nil
+	})
+	return result, err
+}
+
+func (c *Collection[T]) FindOne(conds ...Cond) (T, error) {
+	var result T
+	b, err := c.with(conds)
+	if err != nil {
+		return result, err
+	}
+	err = b.FindOne(func(s *mongo.SingleResult) error {
+		return s.Decode(&result)
+	})
+	return result, err
+}
+
+func (c *Collection[T]) FindByID(id string) (T, error) {
+	return c.FindOne(byID(id))
+}
+
+// Insert stores doc and returns the inserted id, as hex when it is an ObjectID
+func (c *Collection[T]) Insert(doc T) (string, error) {
+	b, err := c.Bom()
+	if err != nil {
+		return "", err
+	}
+	res, err := b.InsertOne(doc)
+	if err != nil {
+		return "", err
+	}
+	if objectID, ok := res.InsertedID.(primitive.ObjectID); ok {
+		return objectID.Hex(), nil
+	}
+	return fmt.Sprint(res.InsertedID), nil
+}
+
+func (c *Collection[T]) UpdateByID(id string, update interface{}) (*mongo.UpdateResult, error) {
+	b, err := c.with([]Cond{byID(id)})
+	if err != nil {
+		return nil, err
+	}
+	return b.UpdateRaw(update)
+}
+
+func byID(id string) Cond {
+	return func(b *Bom) *Bom {
+		return b.WhereEq("_id", ToObj(id))
+	}
+}
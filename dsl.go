@@ -0,0 +1,170 @@
+package bom
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type (
+	// DSLOption restricts what a FromDSL filter may use
+	DSLOption func(*dslConfig)
+	dslConfig struct {
+		fields    map[string]bool
+		operators map[string]bool
+	}
+	dslNode struct {
+		And   []dslNode   `json:"and"`
+		Or    []dslNode   `json:"or"`
+		Not   *dslNode    `json:"not"`
+		Field string      `json:"field"`
+		Op    string      `json:"op"`
+		Value interface{} `json:"value"`
+	}
+)
+
+var dslOperators = map[string]string{
+	"eq":     "",
+	"ne":     "$ne",
+	"gt":     "$gt",
+	"gte":    "$gte",
+	"lt":     "$lt",
+	"lte":    "$lte",
+	"in":     "$in",
+	"nin":    "$nin",
+	"exists": "$exists",
+}
+
+// DSLAllowFields limits the fields a filter may reference
+func DSLAllowFields(fields ...string) DSLOption {
+	return func(c *dslConfig) {
+		c.fields = make(map[string]bool)
+		for _, field := range fields {
+			c.fields[field] = true
+		}
+	}
+}
+
+// DSLAllowOperators limits the operators a filter may use, out of eq, ne, gt, gte, lt, lte, in, nin and exists
+func DSLAllowOperators(operators ...string) DSLOption {
+	return func(c *dslConfig) {
+		c.operators = make(map[string]bool)
+		for _, op := range operators {
+			c.operators[op] = true
+		}
+	}
+}
+
+// FromDSL adds the conditions of a saved JSON filter such as
+// {"and":[{"field":"age","op":"gte","value":18},{"not":{"field":"status","op":"eq","value":"banned"}}]}.
+// A node is either a group ("and", "or" with a list of nodes, "not" with one node) or a field comparison.
+func (b *Bom) FromDSL(data []byte, opts ...DSLOption) (*Bom, error) {
+	config := &dslConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	var root dslNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return b, fmt.Errorf("invalid filter: %w", err)
+	}
+	condition, err := root.build(config)
+	if err != nil {
+		return b, err
+	}
+	for key, value := range condition {
+		b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": key, "value": value})
+	}
+	return b, nil
+}
+
+func (n dslNode) build(config *dslConfig) (primitive.M, error) {
+	switch {
+	case n.And != nil:
+		return buildDSLGroup("$and", n.And, config)
+	case n.Or != nil:
+		return buildDSLGroup("$or", n.Or, config)
+	case n.Not != nil:
+		condition, err := n.Not.build(config)
+		if err != nil {
+			return nil, err
+		}
+		return primitive.M{"$nor": []primitive.M{condition}}, nil
+	}
+	if n.Field == "" {
+		return nil, fmt.Errorf("invalid filter: node needs a field or one of and, or, not")
+	}
+	if config.fields != nil && !config.fields[n.Field] {
+		return nil, fmt.Errorf("field %q is not allowed in filters", n.Field)
+	}
+	operator, ok := dslOperators[n.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown filter operator %q", n.Op)
+	}
+	if config.operators != nil && !config.operators[n.Op] {
+		return nil, fmt.Errorf("operator %q is not allowed in filters", n.Op)
+	}
+	if operator == "" {
+		return primitive.M{n.Field: primitive.M{"$eq": n.Value}}, nil
+	}
+	return primitive.M{n.Field: primitive.M{operator: n.Value}}, nil
+}
+
+func buildDSLGroup(operator string, nodes []dslNode, config *dslConfig) (primitive.M, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("invalid filter: empty %s group", operator[1:])
+	}
+	conditions := make([]primitive.M, 0, len(nodes))
+	for _, node := range nodes {
+		condition, err := node.build(config)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return primitive.M{operator: conditions}, nil
+}
+
+// FromStruct adds a condition for every field of filter tagged `bom:"field,op"`, op being one of the FromDSL
+// operators and eq when left out: `bom:"age,gte"`, `bom:"status"`. Untagged fields are ignored, and so are
+// fields holding their zero value, nil or an empty slice, so optional filters simply stay unset.
+// Use a pointer field to filter on a zero value, a non-nil pointer always counts.
+// A pointer to a struct is accepted, an unknown op makes the query fail.
+func (b *Bom) FromStruct(filter interface{}) *Bom {
+	v := reflect.Indirect(reflect.ValueOf(filter))
+	if v.Kind() != reflect.Struct {
+		b.setErr(fmt.Errorf("filter must be a struct, got %T", filter))
+		return b
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("bom")
+		if !ok || tag == "-" || t.Field(i).PkgPath != "" {
+			continue
+		}
+		value := v.Field(i)
+		if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+			continue
+		}
+		if value.Kind() == reflect.Ptr {
+			value = value.Elem()
+		}
+		field, op := tag, "eq"
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			field, op = tag[:comma], tag[comma+1:]
+		}
+		operator, ok := dslOperators[op]
+		if !ok {
+			b.setErr(fmt.Errorf("unknown operator %q on field %s", op, t.Field(i).Name))
+			return b
+		}
+		condition := value.Interface()
+		if operator != "" {
+			condition = primitive.M{operator: condition}
+		}
+		b.whereConditions = append(b.whereConditions, map[string]interface{}{"field": field, "value": condition})
+	}
+	return b
+}
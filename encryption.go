@@ -0,0 +1,34 @@
+package bom
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Client-side field level encryption is configured on the client, not on the builder:
+//
+//	opts := options.Client().ApplyURI(uri).SetAutoEncryptionOptions(options.AutoEncryption().
+//		SetKeyVaultNamespace("encryption.__keyVault").
+//		SetKmsProviders(kmsProviders).
+//		SetSchemaMap(schemaMap))
+//	client, err := mongo.Connect(ctx, opts)
+//	b, err := bom.New(bom.SetMongoClient(client), ...)
+//
+// The driver then encrypts the values of encrypted fields in filters and documents and decrypts the results.
+// Deterministically encrypted fields only support equality, use WhereEncrypted for them.
+
+// WhereEncrypted matches a deterministically encrypted field by equality. The condition is always emitted
+// as a top-level {field: value}, never merged into $elemMatch, range or $expr operators the driver can't encrypt.
+func (b *Bom) WhereEncrypted(field string, value interface{}) *Bom {
+	b.encryptedConditions = append(b.encryptedConditions, map[string]interface{}{"field": field, "value": value})
+	return b
+}
+
+func (b *Bom) addEncryptedConditions(result primitive.M) {
+	for _, cnd := range b.encryptedConditions {
+		field := cnd["field"].(string)
+		if _, exists := result[field]; !exists {
+			result[field] = cnd["value"]
+			continue
+		}
+		and, _ := result["$and"].([]primitive.M)
+		result["$and"] = append(and, primitive.M{field: cnd["value"]})
+	}
+}
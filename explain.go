@@ -0,0 +1,142 @@
+package bom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrMaxScanExceeded is returned when a query would examine more documents than WithMaxScan allows
+var ErrMaxScanExceeded = errors.New("query examines more documents than the max scan limit")
+
+// QueryAnalysis is the outcome of AnalyzeQuery
+type QueryAnalysis struct {
+	// UsedIndex is false when the winning plan scans the whole collection (COLLSCAN)
+	UsedIndex bool
+	// IndexName is the index of the first IXSCAN of the winning plan
+	IndexName    string
+	DocsExamined int64
+	DocsReturned int64
+	// ExaminedRatio is DocsExamined per returned document, close to 1 for a selective index
+	ExaminedRatio float64
+}
+
+// WithMaxScan refuses to run List, ListWithPagination, All, One, ListMaps and Preview when the query
+// examines more than n documents. $maxScan was removed from the server in 4.2 and the driver doesn't send it,
+// so on every version the check is done client-side: the find is explained with executionStats first,
+// which executes it once more. Meant as a guardrail for ad-hoc admin queries, not for hot paths.
+func (b *Bom) WithMaxScan(n int64) *Bom {
+	b.maxScan = n
+	return b
+}
+
+// explainFind explains the find of the builder bounded by limit and skip, 0 leaves them out
+func (b *Bom) explainFind(ctx context.Context, verbosity string, limit, skip int64) (bson.Raw, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	find := primitive.D{
+		{Key: "find", Value: b.dbCollection},
+		{Key: "filter", Value: b.getCondition()},
+	}
+	if sm, ok := b.getSort(b.sort); ok {
+		find = append(find, primitive.E{Key: "sort", Value: sm})
+	}
+	if projection, ok := b.buildProjection(); ok {
+		find = append(find, primitive.E{Key: "projection", Value: projection})
+	}
+	if b.hint != nil {
+		find = append(find, primitive.E{Key: "hint", Value: b.hint})
+	}
+	if b.collation != nil {
+		find = append(find, primitive.E{Key: "collation", Value: b.collation.ToDocument()})
+	}
+	if limit > 0 {
+		find = append(find, primitive.E{Key: "limit", Value: limit})
+	}
+	if skip > 0 {
+		find = append(find, primitive.E{Key: "skip", Value: skip})
+	}
+	command := primitive.D{
+		{Key: "explain", Value: find},
+		{Key: "verbosity", Value: verbosity},
+	}
+	return b.client.Database(b.dbName).RunCommand(ctx, command).DecodeBytes()
+}
+
+// checkMaxScan explains the find with the limit and skip of the read it guards
+func (b *Bom) checkMaxScan(ctx context.Context, limit, skip int64) error {
+	if b.maxScan <= 0 {
+		return nil
+	}
+	explain, err := b.explainFind(ctx, "executionStats", limit, skip)
+	if err != nil {
+		return err
+	}
+	examined := rawToInt64(explain.Lookup("executionStats", "totalDocsExamined"))
+	if examined > b.maxScan {
+		return fmt.Errorf("%w: %d examined, limit %d", ErrMaxScanExceeded, examined, b.maxScan)
+	}
+	return nil
+}
+
+// AnalyzeQuery explains the find of the builder with executionStats, which executes it once,
+// and reports whether the winning plan used an index and how many documents it examined per returned one
+func (b *Bom) AnalyzeQuery() (*QueryAnalysis, error) {
+	return b.AnalyzeQueryWithContext(context.Background())
+}
+
+// AnalyzeQueryWithContext is AnalyzeQuery bounded by ctx as well as the read timeout
+func (b *Bom) AnalyzeQueryWithContext(ctx context.Context) (*QueryAnalysis, error) {
+	explain, err := b.explainFind(ctx, "executionStats", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	analysis := &QueryAnalysis{
+		DocsExamined: rawToInt64(explain.Lookup("executionStats", "totalDocsExamined")),
+		DocsReturned: rawToInt64(explain.Lookup("executionStats", "nReturned")),
+	}
+	plan, _ := explain.Lookup("queryPlanner", "winningPlan").DocumentOK()
+	// the slot based engine of 7.0+ nests the classic plan under queryPlan
+	if queryPlan, ok := plan.Lookup("queryPlan").DocumentOK(); ok {
+		plan = queryPlan
+	}
+	analysis.IndexName, analysis.UsedIndex = findIndexScan(plan)
+	analysis.ExaminedRatio = float64(analysis.DocsExamined)
+	if analysis.DocsReturned > 0 {
+		analysis.ExaminedRatio /= float64(analysis.DocsReturned)
+	}
+	return analysis, nil
+}
+
+// findIndexScan walks a plan stage and its inputs, returning the index of the first IXSCAN
+func findIndexScan(stage bson.Raw) (string, bool) {
+	if stage == nil {
+		return "", false
+	}
+	if name, _ := stage.Lookup("stage").StringValueOK(); name == "IXSCAN" || name == "COUNT_SCAN" || name == "DISTINCT_SCAN" {
+		index, _ := stage.Lookup("indexName").StringValueOK()
+		return index, true
+	}
+	if input, ok := stage.Lookup("inputStage").DocumentOK(); ok {
+		if index, ok := findIndexScan(input); ok {
+			return index, true
+		}
+	}
+	if inputs, ok := stage.Lookup("inputStages").ArrayOK(); ok {
+		values, _ := inputs.Values()
+		for _, value := range values {
+			if input, ok := value.DocumentOK(); ok {
+				if index, ok := findIndexScan(input); ok {
+					return index, true
+				}
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,128 @@
+package bom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrFieldNotQueryable is returned when a condition uses a field outside of SetAllowedFields
+var ErrFieldNotQueryable = errors.New("field not queryable")
+
+// SetAllowedFields restricts the fields conditions may use, e.g. for filters supplied by API clients.
+// The conditions are still recorded, the query methods then fail with ErrFieldNotQueryable
+// instead of running. Fields match exactly, so "address.city" has to be listed on its own.
+// Operators such as $expr and the raw WithCondition document are not checked.
+func SetAllowedFields(fields ...string) Option {
+	return func(b *Bom) error {
+		b.allowedFields = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			b.allowedFields[field] = true
+		}
+		return nil
+	}
+}
+
+// Err returns the error the query methods would fail with before running, such as ErrFieldNotQueryable.
+// The methods returning a *mongo.SingleResult don't run either, their result error is a mongo.MarshalError
+// wrapping it, check Err before calling them to get it as is.
+func (b *Bom) Err() error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.checkProjection(); err != nil {
+		return err
+	}
+	return b.checkAllowedFields()
+}
+
+// setErr records the first error of the builder calls, it is returned once the query runs
+func (b *Bom) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *Bom) checkAllowedFields() error {
+	if b.allowedFields == nil {
+		return nil
+	}
+	for _, conditions := range [][]map[string]interface{}{
+		b.whereConditions, b.orConditions, b.norConditions, b.rangeConditions, b.encryptedConditions,
+		b.inConditions, b.notInConditions, b.notConditions,
+	} {
+		if err := b.allowConditions(conditions); err != nil {
+			return err
+		}
+	}
+	if b.inChunks != nil {
+		return b.allowField(b.inChunks.field)
+	}
+	return nil
+}
+
+func (b *Bom) allowConditions(conditions []map[string]interface{}) error {
+	for _, cnd := range conditions {
+		if group, ok := cnd["value"].(*Group); ok {
+			if err := b.allowConditions(group.conditions); err != nil {
+				return err
+			}
+			continue
+		}
+		// FromDSL stores its groups as {"$and": [...]}, {"$or": [...]} and {"$nor": [...]}
+		if docs, ok := cnd["value"].([]primitive.M); ok && strings.HasPrefix(cnd["field"].(string), "$") {
+			if err := b.allowDocuments(docs); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.allowField(cnd["field"].(string)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bom) allowDocuments(docs []primitive.M) error {
+	for _, doc := range docs {
+		for field, value := range doc {
+			if nested, ok := value.([]primitive.M); ok && strings.HasPrefix(field, "$") {
+				if err := b.allowDocuments(nested); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := b.allowField(field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// errFilter fails to marshal with err, the driver then returns a SingleResult holding it without running the query
+type errFilter struct {
+	err error
+}
+
+func (f errFilter) MarshalBSON() ([]byte, error) {
+	return nil, f.err
+}
+
+// singleResultFilter is the condition of the methods returning a *mongo.SingleResult, or an errFilter
+// when the builder has an error
+func (b *Bom) singleResultFilter() interface{} {
+	if err := b.Err(); err != nil {
+		return errFilter{err: err}
+	}
+	return b.getCondition()
+}
+
+func (b *Bom) allowField(field string) error {
+	if strings.HasPrefix(field, "$") || b.allowedFields[field] {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrFieldNotQueryable, field)
+}
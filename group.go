@@ -0,0 +1,119 @@
+package bom
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Group collects conditions for a nested document, such as the body of an $elemMatch
+type Group struct {
+	conditions []map[string]interface{}
+}
+
+func newGroup(fn func(*Group)) *Group {
+	g := &Group{}
+	fn(g)
+	return g
+}
+
+func (g *Group) WhereConditions(field string, conditions string, value interface{}) *Group {
+	g.conditions = append(g.conditions, map[string]interface{}{"field": field, "value": conditionValue(conditions, value)})
+	return g
+}
+
+func (g *Group) WhereEq(field string, value interface{}) *Group {
+	return g.WhereConditions(field, "=", value)
+}
+
+func (g *Group) WhereNotEq(field string, value interface{}) *Group {
+	return g.WhereConditions(field, "!=", value)
+}
+
+func (g *Group) WhereGt(field string, value interface{}) *Group {
+	return g.WhereConditions(field, ">", value)
+}
+
+func (g *Group) WhereGte(field string, value interface{}) *Group {
+	return g.WhereConditions(field, ">=", value)
+}
+
+func (g *Group) WhereLt(field string, value interface{}) *Group {
+	return g.WhereConditions(field, "<", value)
+}
+
+func (g *Group) WhereLte(field string, value interface{}) *Group {
+	return g.WhereConditions(field, "<=", value)
+}
+
+func (g *Group) InWhere(field string, value interface{}) *Group {
+	g.conditions = append(g.conditions, map[string]interface{}{"field": field, "value": primitive.M{"$in": value}})
+	return g
+}
+
+func (g *Group) NotInWhere(field string, value interface{}) *Group {
+	g.conditions = append(g.conditions, map[string]interface{}{"field": field, "value": primitive.M{"$nin": value}})
+	return g
+}
+
+// build merges the conditions into one document, repeated fields are ANDed instead of overwritten
+func (g *Group) build() primitive.M {
+	result := make(primitive.M)
+	var and []primitive.M
+	for _, cnd := range g.conditions {
+		field := cnd["field"].(string)
+		value := cnd["value"]
+		if _, exists := result[field]; exists {
+			and = append(and, primitive.M{field: value})
+			continue
+		}
+		result[field] = value
+	}
+	if len(and) > 0 {
+		result["$and"] = and
+	}
+	return result
+}
+
+// buildAnd returns the conditions as an explicit $and of one document per condition, in the order they were added
+func (g *Group) buildAnd() primitive.M {
+	if len(g.conditions) == 0 {
+		return primitive.M{}
+	}
+	and := make([]primitive.M, 0, len(g.conditions))
+	for _, cnd := range g.conditions {
+		and = append(and, primitive.M{cnd["field"].(string): cnd["value"]})
+	}
+	return primitive.M{"$and": and}
+}
+
+func conditionValue(conditions string, value interface{}) interface{} {
+	switch conditions {
+	case ">":
+		return primitive.D{{Key: "$gt", Value: value}}
+	case ">=":
+		return primitive.D{{Key: "$gte", Value: value}}
+	case "<":
+		return primitive.D{{Key: "$lt", Value: value}}
+	case "<=":
+		return primitive.D{{Key: "$lte", Value: value}}
+	case "!=":
+		return primitive.D{{Key: "$ne", Value: value}}
+	}
+	return value
+}
+
+// exprOperator maps a comparison to its aggregation expression operator, anything unknown is equality
+func exprOperator(conditions string) string {
+	switch conditions {
+	case ">":
+		return "$gt"
+	case ">=":
+		return "$gte"
+	case "<":
+		return "$lt"
+	case "<=":
+		return "$lte"
+	case "!=":
+		return "$ne"
+	}
+	return "$eq"
+}
@@ -0,0 +1,72 @@
+package bom
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var rangeOperators = map[string]bool{"$gt": true, "$gte": true, "$lt": true, "$lte": true, "$ne": true}
+
+// SuggestIndex returns the compound index that serves the current where conditions and sort,
+// following the equality, sort, range rule. MongoDB can't be hinted to intersect several indexes,
+// when the planner picks badly for two filtered fields a compound index like this one is the fix.
+func (b *Bom) SuggestIndex() bson.D {
+	var equality, ranges []string
+	for _, cnd := range b.whereConditions {
+		field := cnd["field"].(string)
+		if strings.HasPrefix(field, "$") {
+			continue
+		}
+		if isRangeCondition(cnd["value"]) {
+			ranges = append(ranges, field)
+		} else {
+			equality = append(equality, field)
+		}
+	}
+	for _, cnd := range b.inConditions {
+		equality = append(equality, cnd["field"].(string))
+	}
+	for _, cnd := range b.rangeConditions {
+		ranges = append(ranges, cnd["field"].(string))
+	}
+
+	var index bson.D
+	seen := make(map[string]bool)
+	add := func(field string, direction interface{}) {
+		if !seen[field] {
+			seen[field] = true
+			index = append(index, primitive.E{Key: field, Value: direction})
+		}
+	}
+	for _, field := range equality {
+		add(field, 1)
+	}
+	sort, _ := b.getSort(b.sort)
+	for _, key := range sort {
+		add(key.Key, key.Value)
+	}
+	for _, field := range ranges {
+		add(field, 1)
+	}
+	return index
+}
+
+func isRangeCondition(value interface{}) bool {
+	switch v := value.(type) {
+	case primitive.D:
+		for _, e := range v {
+			if rangeOperators[e.Key] {
+				return true
+			}
+		}
+	case primitive.M:
+		for key := range v {
+			if rangeOperators[key] {
+				return true
+			}
+		}
+	}
+	return false
+}
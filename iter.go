@@ -0,0 +1,75 @@
+package bom
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Iter streams the documents of a query one by one, it must be closed when done
+type Iter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cursor *mongo.Cursor
+}
+
+func (it *Iter) Next() bool {
+	return it.cursor.Next(it.ctx)
+}
+
+func (it *Iter) Decode(val interface{}) error {
+	return it.cursor.Decode(val)
+}
+
+func (it *Iter) Err() error {
+	return it.cursor.Err()
+}
+
+func (it *Iter) Close() error {
+	defer it.cancel()
+	return it.cursor.Close(it.ctx)
+}
+
+// PageIter counts the matching documents and returns an iterator over the current page,
+// so a large page can be decoded one document at a time. The read timeout covers the whole iteration.
+func (b *Bom) PageIter() (*Iter, *Pagination, error) {
+	return b.PageIterWithContext(context.Background())
+}
+
+// PageIterWithContext is PageIter with the count and the iteration bounded by ctx as well as the read timeout
+func (b *Bom) PageIterWithContext(ctx context.Context) (*Iter, *Pagination, error) {
+	if err := b.Err(); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	findOptions := b.newFindOptions(ctx)
+	limit, offset := b.calculateOffset(b.limit.Page, b.limit.Size)
+	findOptions.SetLimit(int64(limit)).SetSkip(int64(offset))
+	if sm, ok := b.getSort(b.sort); ok {
+		findOptions.SetSort(sm)
+	}
+	if projection, ok := b.buildProjection(); ok {
+		findOptions.SetProjection(projection)
+	}
+
+	condition := b.getCondition()
+	var count int64
+	var err error
+	if bs, ok := condition.(primitive.M); ok && len(bs) == 0 {
+		count, err = b.readQuery().EstimatedDocumentCount(ctx)
+	} else {
+		count, err = b.readQuery().CountDocuments(ctx, condition, b.newCountOptions())
+	}
+	if err != nil {
+		cancel()
+		return nil, &Pagination{}, err
+	}
+	cur, err := b.readQuery().Find(ctx, condition, findOptions)
+	if err != nil {
+		cancel()
+		return nil, &Pagination{}, err
+	}
+	pagination := b.getPagination(int32(count), b.limit.Page, b.limit.Size)
+	return &Iter{ctx: ctx, cancel: cancel, cursor: cur}, pagination, nil
+}
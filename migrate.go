@@ -0,0 +1,99 @@
+package bom
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrateField moves the value of from into to through transform, batchSize documents at a time, and unsets from.
+// Only documents that still have from are picked up, so an interrupted migration can simply be run again.
+func (b *Bom) MigrateField(from, to string, transform func(old interface{}) interface{}, batchSize int) (migrated int64, err error) {
+	return b.MigrateFieldWithContext(context.Background(), from, to, transform, batchSize)
+}
+
+// MigrateFieldWithContext is MigrateField stopped by ctx between and within batches,
+// each batch read and write is still bounded by its own timeout
+func (b *Bom) MigrateFieldWithContext(ctx context.Context, from, to string, transform func(old interface{}) interface{}, batchSize int) (migrated int64, err error) {
+	if err := b.Err(); err != nil {
+		return 0, err
+	}
+	if batchSize < 1 {
+		batchSize = DefaultSize
+	}
+	pending := primitive.M{"$and": []interface{}{b.getCondition(), primitive.M{from: primitive.M{"$exists": true}}}}
+	for {
+		models, err := b.migrationBatch(ctx, pending, from, to, transform, batchSize)
+		if err != nil || len(models) == 0 {
+			return migrated, err
+		}
+		writeCtx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+		res, err := b.Mongo().BulkWrite(writeCtx, models, b.newBulkWriteOptions().SetOrdered(false))
+		cancel()
+		if res != nil {
+			migrated += res.ModifiedCount
+		}
+		if err != nil {
+			return migrated, err
+		}
+		if res.ModifiedCount == 0 {
+			return migrated, nil
+		}
+	}
+}
+
+func (b *Bom) migrationBatch(ctx context.Context, pending primitive.M, from, to string, transform func(old interface{}) interface{}, batchSize int) (_ []mongo.WriteModel, err error) {
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	findOptions := options.Find().
+		SetLimit(int64(batchSize)).
+		SetProjection(primitive.M{"_id": 1, from: 1})
+	cur, err := b.Mongo().Find(ctx, pending, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer closeCursor(ctx, cur, &err)
+
+	var models []mongo.WriteModel
+	for cur.Next(ctx) {
+		var doc bson.M
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		old, _ := lookupPath(doc, from)
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(primitive.M{"_id": doc["_id"], from: primitive.M{"$exists": true}}).
+			SetUpdate(primitive.D{
+				{Key: "$set", Value: primitive.M{to: transform(old)}},
+				{Key: "$unset", Value: primitive.M{from: ""}},
+			}))
+	}
+	return models, cur.Err()
+}
+
+func lookupPath(doc bson.M, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case bson.M:
+			val, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		case primitive.D:
+			val, ok := v.Map()[key]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,43 @@
+package bom
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PartialResultsError is returned with WithPartialResults when the read failed after some documents were already read,
+// Err is the cause and errors.Is/As see through to it
+type PartialResultsError struct {
+	// Processed is the number of documents handed to the callback, or returned, before the failure
+	Processed int
+	Err       error
+}
+
+func (e *PartialResultsError) Error() string {
+	return fmt.Sprintf("read failed after %d documents: %v", e.Processed, e.Err)
+}
+
+func (e *PartialResultsError) Unwrap() error {
+	return e.Err
+}
+
+// WithPartialResults keeps what was read before a cursor or chunk failure instead of discarding it:
+// All and ListMaps fill in the documents read so far and List reports how many went through the callback,
+// in both cases together with a *PartialResultsError. An error returned by the callback itself is never wrapped.
+// Nothing is retried, a follow-up query has to skip what was processed, e.g. with a condition on _id.
+func (b *Bom) WithPartialResults() *Bom {
+	b.partialResults = true
+	return b
+}
+
+func (b *Bom) partialError(processed int, err error) error {
+	if err == nil || !b.partialResults || processed == 0 {
+		return err
+	}
+	return &PartialResultsError{Processed: processed, Err: err}
+}
+
+func isPartialResults(err error) bool {
+	var partial *PartialResultsError
+	return errors.As(err, &partial)
+}
@@ -0,0 +1,390 @@
+package bom
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UnionWith appends a $unionWith stage that combines the documents of coll with b.dbCollection.
+// Requires MongoDB 4.4+. The builder's conditions only filter b.dbCollection,
+// pass a pipeline to filter the unioned collection as well.
+func (b *Bom) UnionWith(coll string, pipeline ...primitive.D) *Bom {
+	union := primitive.D{{Key: "coll", Value: coll}}
+	if len(pipeline) > 0 {
+		union = append(union, primitive.E{Key: "pipeline", Value: pipeline})
+	}
+	b.stages = append(b.stages, primitive.D{{Key: "$unionWith", Value: union}})
+	return b
+}
+
+// GeoNear makes the pipeline start with a $geoNear stage which writes the distance in meters
+// from near into distanceField, a maxMeters of 0 means no limit.
+// $geoNear must be the first stage, so the builder's conditions are passed as its query
+// instead of a separate $match. Requires a 2dsphere index.
+func (b *Bom) GeoNear(near GeoJSON, distanceField string, maxMeters float64) *Bom {
+	geoNear := primitive.D{
+		{Key: "near", Value: near},
+		{Key: "distanceField", Value: distanceField},
+		{Key: "spherical", Value: true},
+	}
+	if maxMeters > 0 {
+		geoNear = append(geoNear, primitive.E{Key: "maxDistance", Value: maxMeters})
+	}
+	b.geoNear = geoNear
+	return b
+}
+
+// Bucket appends a $bucket stage grouping documents into [boundaries[i], boundaries[i+1]) ranges of groupBy,
+// documents outside the boundaries go to defaultBucket (nil to drop them), a nil output counts documents per bucket
+func (b *Bom) Bucket(groupBy interface{}, boundaries []interface{}, defaultBucket interface{}, output interface{}) *Bom {
+	bucket := primitive.D{
+		{Key: "groupBy", Value: groupBy},
+		{Key: "boundaries", Value: boundaries},
+	}
+	if defaultBucket != nil {
+		bucket = append(bucket, primitive.E{Key: "default", Value: defaultBucket})
+	}
+	if output != nil {
+		bucket = append(bucket, primitive.E{Key: "output", Value: output})
+	}
+	b.stages = append(b.stages, primitive.D{{Key: "$bucket", Value: bucket}})
+	return b
+}
+
+// BucketAuto appends a $bucketAuto stage which picks the boundaries itself to spread documents evenly into buckets
+func (b *Bom) BucketAuto(groupBy interface{}, buckets int, output interface{}) *Bom {
+	bucket := primitive.D{
+		{Key: "groupBy", Value: groupBy},
+		{Key: "buckets", Value: buckets},
+	}
+	if output != nil {
+		bucket = append(bucket, primitive.E{Key: "output", Value: output})
+	}
+	b.stages = append(b.stages, primitive.D{{Key: "$bucketAuto", Value: bucket}})
+	return b
+}
+
+// GraphLookup appends a $graphLookup stage collecting into as every document of from reachable by
+// following connectFromField to connectToField recursively, starting with the startWith expression ("$parentId").
+// maxDepth 0 only follows one hop, a negative maxDepth doesn't limit the recursion.
+// A depthField, when given, stores the hop count in each collected document.
+func (b *Bom) GraphLookup(from, startWith, connectFromField, connectToField, as string, maxDepth int, depthField ...string) *Bom {
+	graphLookup := primitive.D{
+		{Key: "from", Value: from},
+		{Key: "startWith", Value: startWith},
+		{Key: "connectFromField", Value: connectFromField},
+		{Key: "connectToField", Value: connectToField},
+		{Key: "as", Value: as},
+	}
+	if maxDepth >= 0 {
+		graphLookup = append(graphLookup, primitive.E{Key: "maxDepth", Value: maxDepth})
+	}
+	if len(depthField) > 0 && depthField[0] != "" {
+		graphLookup = append(graphLookup, primitive.E{Key: "depthField", Value: depthField[0]})
+	}
+	b.stages = append(b.stages, primitive.D{{Key: "$graphLookup", Value: graphLookup}})
+	return b
+}
+
+// ProjectMeta appends a $project of the listed fields plus the metadata keyword as field as.
+// Valid keywords: "textScore" after a $text match, "searchScore" and "searchHighlights" after an Atlas $search stage,
+// "indexKey" for the index key of the document (4.4+). $project drops every field that isn't listed.
+func (b *Bom) ProjectMeta(as, keyword string, fields ...string) *Bom {
+	project := make(primitive.D, 0, len(fields)+1)
+	for _, field := range fields {
+		project = append(project, primitive.E{Key: field, Value: 1})
+	}
+	project = append(project, primitive.E{Key: as, Value: primitive.M{"$meta": keyword}})
+	b.stages = append(b.stages, primitive.D{{Key: "$project", Value: project}})
+	return b
+}
+
+// SortMeta appends a $sort by the metadata keyword, descending by relevance.
+// Sorting by {$meta: ...} accepts "textScore" on any version and "searchScore" on Atlas Search only,
+// for other keywords project the value with ProjectMeta first and sort by that field.
+func (b *Bom) SortMeta(field, keyword string) *Bom {
+	b.stages = append(b.stages, primitive.D{{Key: "$sort", Value: primitive.D{{Key: field, Value: primitive.M{"$meta": keyword}}}}})
+	return b
+}
+
+func (b *Bom) buildPipeline() mongo.Pipeline {
+	var pipeline mongo.Pipeline
+	condition := b.getCondition()
+	cnd, ok := condition.(primitive.M)
+	hasCondition := !ok || len(cnd) > 0
+	if b.geoNear != nil {
+		geoNear := b.geoNear
+		if hasCondition {
+			geoNear = append(append(primitive.D{}, geoNear...), primitive.E{Key: "query", Value: condition})
+		}
+		pipeline = append(pipeline, primitive.D{{Key: "$geoNear", Value: geoNear}})
+	} else if hasCondition {
+		pipeline = append(pipeline, primitive.D{{Key: "$match", Value: condition}})
+	}
+	if stage, ok := b.projectionStage(); ok {
+		pipeline = append(pipeline, stage)
+	}
+	return append(pipeline, b.stages...)
+}
+
+// projectionStage is the $project stage of the builder's projection, Select, WithProjection
+// or else SetDefaultProjection, so aggregations return the same fields as finds
+func (b *Bom) projectionStage() (primitive.D, bool) {
+	projection, ok := b.buildProjection()
+	if !ok {
+		return nil, false
+	}
+	return primitive.D{{Key: "$project", Value: projection}}, true
+}
+
+// ListAggregate runs the builder's pipeline, conditions as $match followed by the added stages
+func (b *Bom) ListAggregate(callback func(cursor *mongo.Cursor) error) error {
+	return b.AggregateWithContext(context.Background(), nil, callback)
+}
+
+// ListAggregateWithContext is ListAggregate bounded by ctx as well as the read timeout
+func (b *Bom) ListAggregateWithContext(ctx context.Context, callback func(cursor *mongo.Cursor) error) error {
+	return b.AggregateWithContext(ctx, nil, callback)
+}
+
+// Aggregate runs pipeline after the builder's own stages, the conditions as $match and the stages added
+// with UnionWith, Bucket, GraphLookup and the like, and calls callback for every result document.
+// With WithoutAutoMatch pipeline runs exactly as given.
+func (b *Bom) Aggregate(pipeline mongo.Pipeline, callback func(cursor *mongo.Cursor) error) error {
+	return b.AggregateWithContext(context.Background(), pipeline, callback)
+}
+
+// AggregateWithContext is Aggregate bounded by ctx as well as the read timeout
+func (b *Bom) AggregateWithContext(ctx context.Context, pipeline mongo.Pipeline, callback func(cursor *mongo.Cursor) error) error {
+	if b.noAutoMatch {
+		return b.runPipeline(ctx, pipeline, callback)
+	}
+	return b.runPipeline(ctx, append(b.buildPipeline(), pipeline...), callback)
+}
+
+// WithoutAutoMatch makes Aggregate skip the builder's conditions and stages
+func (b *Bom) WithoutAutoMatch() *Bom {
+	b.noAutoMatch = true
+	return b
+}
+
+func (b *Bom) runPipeline(ctx context.Context, pipeline mongo.Pipeline, callback func(cursor *mongo.Cursor) error) (err error) {
+	if err := b.Err(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	cur, err := b.readQuery().Aggregate(ctx, pipeline, b.getAggregateOptions()...)
+	if err != nil {
+		return err
+	}
+	defer closeCursor(ctx, cur, &err)
+	for cur.Next(ctx) {
+		if err = callback(cur); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// AggregateAll runs pipeline without the builder's conditions, only preceded by the builder's projection,
+// and decodes every result document into T, which usually has the grouped or projected shape of the output
+// rather than the collection's document
+func AggregateAll[T any](b *Bom, pipeline mongo.Pipeline) ([]T, error) {
+	return AggregateAllWithContext[T](context.Background(), b, pipeline)
+}
+
+// AggregateAllWithContext is AggregateAll bounded by ctx as well as the read timeout
+func AggregateAllWithContext[T any](ctx context.Context, b *Bom, pipeline mongo.Pipeline) ([]T, error) {
+	if stage, ok := b.projectionStage(); ok {
+		pipeline = append(mongo.Pipeline{stage}, pipeline...)
+	}
+	var results []T
+	err := b.runPipeline(ctx, pipeline, func(cursor *mongo.Cursor) error {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return err
+		}
+		results = append(results, item)
+		return nil
+	})
+	return results, err
+}
+
+// DistinctCombo returns every distinct combination of the fields among the matching documents,
+// each one as a map from field name to value
+func (b *Bom) DistinctCombo(fields ...string) ([]bson.M, error) {
+	return b.DistinctComboWithContext(context.Background(), fields...)
+}
+
+// DistinctComboWithContext is DistinctCombo bounded by ctx as well as the read timeout
+func (b *Bom) DistinctComboWithContext(ctx context.Context, fields ...string) ([]bson.M, error) {
+	id := make(primitive.D, 0, len(fields))
+	for i, field := range fields {
+		id = append(id, primitive.E{Key: fmt.Sprintf("f%d", i), Value: "$" + field})
+	}
+	pipeline := append(b.buildPipeline(),
+		primitive.D{{Key: "$group", Value: primitive.D{{Key: "_id", Value: id}}}},
+		primitive.D{{Key: "$sort", Value: primitive.D{{Key: "_id", Value: 1}}}},
+	)
+	var combos []bson.M
+	err := b.runPipeline(ctx, pipeline, func(cursor *mongo.Cursor) error {
+		group, err := cursor.Current.LookupErr("_id")
+		if err != nil {
+			return err
+		}
+		values := make(bson.M, len(fields))
+		for i, field := range fields {
+			var value interface{}
+			if val, err := group.Document().LookupErr(fmt.Sprintf("f%d", i)); err == nil {
+				if err := val.Unmarshal(&value); err != nil {
+					return err
+				}
+			}
+			values[field] = value
+		}
+		if b.hexObjectIDs {
+			HexObjectIDs(values)
+		}
+		combos = append(combos, values)
+		return nil
+	})
+	return combos, err
+}
+
+// GroupCount is one group of TopN, the value of the grouped field and the number of documents having it
+type GroupCount struct {
+	Key   interface{}
+	Count int64
+}
+
+// TopN counts the matching documents per value of groupField and returns the n largest groups,
+// ties ordered by value. Documents missing the field are counted under a nil Key.
+func (b *Bom) TopN(groupField string, n int) ([]GroupCount, error) {
+	return b.TopNWithContext(context.Background(), groupField, n)
+}
+
+// TopNWithContext is TopN bounded by ctx as well as the read timeout
+func (b *Bom) TopNWithContext(ctx context.Context, groupField string, n int) ([]GroupCount, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("top n must be positive, got %d", n)
+	}
+	pipeline := append(b.buildPipeline(),
+		primitive.D{{Key: "$group", Value: primitive.D{
+			{Key: "_id", Value: "$" + groupField},
+			{Key: "count", Value: primitive.M{"$sum": 1}},
+		}}},
+		primitive.D{{Key: "$sort", Value: primitive.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		primitive.D{{Key: "$limit", Value: n}},
+	)
+	var groups []GroupCount
+	err := b.runPipeline(ctx, pipeline, func(cursor *mongo.Cursor) error {
+		var key interface{}
+		if err := cursor.Current.Lookup("_id").Unmarshal(&key); err != nil {
+			return err
+		}
+		if b.hexObjectIDs {
+			key = HexObjectIDs(key)
+		}
+		groups = append(groups, GroupCount{Key: key, Count: rawToInt64(cursor.Current.Lookup("count"))})
+		return nil
+	})
+	return groups, err
+}
+
+// DistinctCI returns the distinct string values of field ignoring case: "USA", "Usa" and "usa" collapse
+// into one entry, spelled as the first of them met by the $group. Non-string values are skipped.
+// The result is ordered by the lowercase value.
+func (b *Bom) DistinctCI(field string) ([]string, error) {
+	return b.DistinctCIWithContext(context.Background(), field)
+}
+
+// DistinctCIWithContext is DistinctCI bounded by ctx as well as the read timeout
+func (b *Bom) DistinctCIWithContext(ctx context.Context, field string) ([]string, error) {
+	pipeline := append(b.buildPipeline(),
+		primitive.D{{Key: "$match", Value: primitive.M{field: primitive.M{"$type": "string"}}}},
+		primitive.D{{Key: "$group", Value: primitive.D{
+			{Key: "_id", Value: primitive.M{"$toLower": "$" + field}},
+			{Key: "value", Value: primitive.M{"$first": "$" + field}},
+		}}},
+		primitive.D{{Key: "$sort", Value: primitive.D{{Key: "_id", Value: 1}}}},
+	)
+	var values []string
+	err := b.runPipeline(ctx, pipeline, func(cursor *mongo.Cursor) error {
+		if value, ok := cursor.Current.Lookup("value").StringValueOK(); ok {
+			values = append(values, value)
+		}
+		return nil
+	})
+	return values, err
+}
+
+func (b *Bom) AggregateWithPagination(callback func(cursor *mongo.Cursor) error) (*Pagination, error) {
+	return b.AggregateWithPaginationWithContext(context.Background(), callback)
+}
+
+// AggregateWithPaginationWithContext is AggregateWithPagination bounded by ctx as well as the read timeout,
+// the count and the page share the same deadline
+func (b *Bom) AggregateWithPaginationWithContext(ctx context.Context, callback func(cursor *mongo.Cursor) error) (_ *Pagination, err error) {
+	if err := b.Err(); err != nil {
+		return &Pagination{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	pipeline := b.buildPipeline()
+
+	countPipeline := append(append(mongo.Pipeline{}, pipeline...), primitive.D{{Key: "$count", Value: "total"}})
+	countCur, err := b.readQuery().Aggregate(ctx, countPipeline, b.getAggregateOptions()...)
+	if err != nil {
+		return &Pagination{}, err
+	}
+	var count int64
+	if countCur.Next(ctx) {
+		count = rawToInt64(countCur.Current.Lookup("total"))
+	}
+	err = countCur.Err()
+	closeCursor(ctx, countCur, &err)
+	if err != nil {
+		return &Pagination{}, err
+	}
+
+	if sm, ok := b.getSort(b.sort); ok {
+		pipeline = append(pipeline, primitive.D{{Key: "$sort", Value: sm}})
+	}
+	limit, offset := b.calculateOffset(b.limit.Page, b.limit.Size)
+	pipeline = append(pipeline,
+		primitive.D{{Key: "$skip", Value: int64(offset)}},
+		primitive.D{{Key: "$limit", Value: int64(limit)}},
+	)
+	cur, err := b.readQuery().Aggregate(ctx, pipeline, b.getAggregateOptions()...)
+	if err != nil {
+		return &Pagination{}, err
+	}
+	defer closeCursor(ctx, cur, &err)
+	for cur.Next(ctx) {
+		if err = callback(cur); err != nil {
+			return &Pagination{}, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return &Pagination{}, err
+	}
+	return b.getPagination(int32(count), b.limit.Page, b.limit.Size), nil
+}
+
+func rawToInt64(val bson.RawValue) int64 {
+	switch val.Type {
+	case bsontype.Int32:
+		return int64(val.Int32())
+	case bsontype.Int64:
+		return val.Int64()
+	case bsontype.Double:
+		return int64(val.Double())
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package bom
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestBuildPipelineProjection(t *testing.T) {
+	hidden := bson.M{"passwordHash": 0}
+	got := newTestBom(t, SetDefaultProjection(hidden)).buildPipeline()
+	want := mongo.Pipeline{{{Key: "$project", Value: hidden}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pipeline = %v, want %v", got, want)
+	}
+
+	// an inclusion projection replaces the default one, passwordHash stays hidden as it isn't selected
+	got = newTestBom(t, SetDefaultProjection(hidden)).Select("name").buildPipeline()
+	want = mongo.Pipeline{{{Key: "$project", Value: primitive.M{"name": 1}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pipeline with Select = %v, want %v", got, want)
+	}
+	got = newTestBom(t, SetDefaultProjection(hidden)).WithProjection(bson.M{"name": 1}).buildPipeline()
+	want = mongo.Pipeline{{{Key: "$project", Value: bson.M{"name": 1}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pipeline with WithProjection = %v, want %v", got, want)
+	}
+}
+
+func TestFindOneAndUpdateDefaultProjection(t *testing.T) {
+	hidden := bson.M{"passwordHash": 0}
+	opts := newTestBom(t, SetDefaultProjection(hidden)).getFindOneAndUpdateOptions()
+	if len(opts) != 1 || !reflect.DeepEqual(opts[0].Projection, hidden) {
+		t.Errorf("options = %v, want the default projection", opts)
+	}
+	opts = newTestBom(t, SetDefaultProjection(hidden)).Select("name").getFindOneAndUpdateOptions()
+	if len(opts) != 1 || !reflect.DeepEqual(opts[0].Projection, primitive.M{"name": 1}) {
+		t.Errorf("options with Select = %v, want the selected projection", opts)
+	}
+}
+
+func TestBuildPipelineAutoMatch(t *testing.T) {
+	got := newTestBom(t).Where("kind", "a").buildPipeline()
+	want := mongo.Pipeline{{{Key: "$match", Value: bson.M{"$and": []bson.M{{"kind": "a"}}}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pipeline = %v, want %v", got, want)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	b := newMongoBom(t)
+	seed(t, b, bson.M{"kind": "a"}, bson.M{"kind": "a"}, bson.M{"kind": "b"})
+	count := mongo.Pipeline{{{Key: "$group", Value: bson.M{"_id": nil, "n": bson.M{"$sum": 1}}}}}
+
+	total := func(b *Bom) int32 {
+		t.Helper()
+		var n int32
+		err := b.Aggregate(count, func(cur *mongo.Cursor) error {
+			n = cur.Current.Lookup("n").Int32()
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := total(newMongoBomOn(t, b).Where("kind", "a")); n != 2 {
+		t.Errorf("Aggregate with the auto $match counted %d, want 2", n)
+	}
+	if n := total(newMongoBomOn(t, b).Where("kind", "a").WithoutAutoMatch()); n != 3 {
+		t.Errorf("Aggregate WithoutAutoMatch counted %d, want 3", n)
+	}
+}
@@ -0,0 +1,140 @@
+package bom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ParallelScan reads every matching document with workers concurrent cursors, each one scanning its own _id range.
+// The ranges are cut by the ObjectID timestamps between the smallest and the largest _id, collections with
+// other _id types are scanned by a single cursor. fn is called concurrently and must be safe for that.
+// The first error stops the other workers, the scan is not bound by the query timeout.
+func (b *Bom) ParallelScan(workers int, fn func(doc bson.Raw) error) error {
+	return b.ParallelScanWithContext(context.Background(), workers, fn)
+}
+
+// ParallelScanWithContext is ParallelScan stopped by ctx, canceling it stops every worker
+func (b *Bom) ParallelScanWithContext(ctx context.Context, workers int, fn func(doc bson.Raw) error) error {
+	if err := b.Err(); err != nil {
+		return err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ranges, err := b.scanRanges(ctx, workers)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, rng := range ranges {
+		wg.Add(1)
+		go func(rng primitive.M) {
+			defer wg.Done()
+			if err := b.scanRange(ctx, rng, fn); err != nil {
+				// the errors after the first one come from the cancellation it triggers
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(rng)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return fmt.Errorf("parallel scan: %w", firstErr)
+	}
+	return nil
+}
+
+func (b *Bom) scanRanges(ctx context.Context, workers int) ([]primitive.M, error) {
+	first, err := b.edgeID(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	last, err := b.edgeID(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	if first == nil || last == nil {
+		return []primitive.M{{}}, nil
+	}
+	from, ok := first.(primitive.ObjectID)
+	if !ok {
+		return []primitive.M{{}}, nil
+	}
+	to, ok := last.(primitive.ObjectID)
+	if !ok {
+		return []primitive.M{{}}, nil
+	}
+
+	start, end := from.Timestamp(), to.Timestamp()
+	step := end.Sub(start) / time.Duration(workers)
+	if step < time.Second {
+		return []primitive.M{{"_id": primitive.M{"$gte": from, "$lte": to}}}, nil
+	}
+	var ranges []primitive.M
+	lower := from
+	for i := 1; i < workers; i++ {
+		upper := objectIDAt(start.Add(step * time.Duration(i)))
+		ranges = append(ranges, primitive.M{"_id": primitive.M{"$gte": lower, "$lt": upper}})
+		lower = upper
+	}
+	return append(ranges, primitive.M{"_id": primitive.M{"$gte": lower, "$lte": to}}), nil
+}
+
+func (b *Bom) edgeID(ctx context.Context, direction int) (interface{}, error) {
+	findOneOptions := options.FindOne().
+		SetSort(primitive.D{{Key: "_id", Value: direction}}).
+		SetProjection(primitive.M{"_id": 1})
+	raw, err := b.readQuery().FindOne(ctx, b.getCondition(), findOneOptions).DecodeBytes()
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc.ID, nil
+}
+
+func (b *Bom) scanRange(ctx context.Context, rng primitive.M, fn func(doc bson.Raw) error) (err error) {
+	condition := primitive.M{"$and": []interface{}{b.getCondition(), rng}}
+	findOptions := b.newFindOptions(ctx)
+	if projection, ok := b.buildProjection(); ok {
+		findOptions.SetProjection(projection)
+	}
+	cur, err := b.readQuery().Find(ctx, condition, findOptions)
+	if err != nil {
+		return err
+	}
+	defer closeCursor(ctx, cur, &err)
+	for cur.Next(ctx) {
+		if err := fn(cur.Current); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
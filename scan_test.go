@@ -0,0 +1,58 @@
+package bom
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParallelScan(t *testing.T) {
+	b := newMongoBom(t)
+	start := time.Now().Add(-100 * time.Hour)
+	var docs []interface{}
+	for i := 0; i < 100; i++ {
+		docs = append(docs, bson.M{"_id": primitive.NewObjectIDFromTimestamp(start.Add(time.Duration(i) * time.Hour))})
+	}
+	seed(t, b, docs...)
+
+	var n int64
+	err := b.ParallelScan(4, func(doc bson.Raw) error {
+		atomic.AddInt64(&n, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 100 {
+		t.Errorf("scanned %d documents, want 100", n)
+	}
+
+	errStop := errors.New("stop")
+	err = b.ParallelScan(4, func(doc bson.Raw) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("ParallelScan error = %v, want %v", err, errStop)
+	}
+}
+
+func TestScanBoundariesZeroTailed(t *testing.T) {
+	id := objectIDAt(time.Unix(1700000000, 0))
+	if id.Timestamp().Unix() != 1700000000 || id.Hex()[8:] != "0000000000000000" {
+		t.Errorf("objectIDAt = %s, want the timestamp followed by zeros", id.Hex())
+	}
+}
+
+func TestParallelScanCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := newTestBom(t).ParallelScanWithContext(ctx, 4, func(doc bson.Raw) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
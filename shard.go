@@ -0,0 +1,66 @@
+package bom
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetShardKey declares the shard key of the collection, queries that don't filter on every
+// shard key field are reported through the logger since they are broadcast to all shards
+func SetShardKey(fields ...string) Option {
+	return func(b *Bom) error {
+		b.shardKey = fields
+		return nil
+	}
+}
+
+// WhereShardKey adds an equality condition for each shard key field so the query targets a single shard
+func (b *Bom) WhereShardKey(values map[string]interface{}) *Bom {
+	for _, field := range b.shardKey {
+		if value, ok := values[field]; ok {
+			b.WhereEq(field, value)
+		}
+	}
+	return b
+}
+
+// checkShardKey warns once per builder about a condition that misses a shard key field
+func (b *Bom) checkShardKey(condition interface{}) {
+	if len(b.shardKey) == 0 || b.shardKeyWarned {
+		return
+	}
+	fields := make(map[string]bool)
+	collectFields(condition, fields)
+	for _, field := range b.shardKey {
+		if !fields[field] {
+			b.shardKeyWarned = true
+			b.warnf("bom: query on %s.%s omits shard key field %q and will be scatter-gather", b.dbName, b.dbCollection, field)
+			return
+		}
+	}
+}
+
+// collectFields gathers the top-level field names of a condition, including the ones ANDed together
+func collectFields(condition interface{}, fields map[string]bool) {
+	switch c := condition.(type) {
+	case primitive.M:
+		for key, value := range c {
+			if key == "$and" {
+				collectFields(value, fields)
+				continue
+			}
+			fields[key] = true
+		}
+	case primitive.D:
+		collectFields(c.Map(), fields)
+	case []primitive.M:
+		for _, item := range c {
+			collectFields(item, fields)
+		}
+	case []interface{}:
+		for _, item := range c {
+			collectFields(item, fields)
+		}
+	case primitive.A:
+		collectFields([]interface{}(c), fields)
+	}
+}
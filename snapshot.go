@@ -0,0 +1,30 @@
+package bom
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// WithSnapshot runs fn in a session whose reads all see the data at one point in time, for reports
+// made of several queries. Pass the ctx fn receives to the WithContext methods of any builder on the same client.
+// The snapshot is a read-only transaction with readConcern "snapshot", so the transaction constraints apply:
+// a replica set (4.0+) or sharded cluster (4.2+), reads from the primary and at most 60 seconds by default.
+func (b *Bom) WithSnapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	return b.client.UseSession(ctx, func(sc mongo.SessionContext) error {
+		txnOptions := options.Transaction().
+			SetReadConcern(readconcern.Snapshot()).
+			SetReadPreference(readpref.Primary())
+		if err := sc.StartTransaction(txnOptions); err != nil {
+			return err
+		}
+		if err := fn(sc); err != nil {
+			_ = sc.AbortTransaction(context.Background())
+			return err
+		}
+		return sc.CommitTransaction(context.Background())
+	})
+}
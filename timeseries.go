@@ -0,0 +1,32 @@
+package bom
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateTimeSeriesCollection creates b.dbCollection as a time-series collection (MongoDB 5.0+).
+// metaField and granularity ("seconds", "minutes" or "hours") are optional and skipped when empty.
+// The create command is sent directly since the driver has no time-series collection options.
+func (b *Bom) CreateTimeSeriesCollection(timeField, metaField string, granularity string) error {
+	return b.CreateTimeSeriesCollectionWithContext(context.Background(), timeField, metaField, granularity)
+}
+
+// CreateTimeSeriesCollectionWithContext is CreateTimeSeriesCollection bounded by ctx as well as the write timeout
+func (b *Bom) CreateTimeSeriesCollectionWithContext(ctx context.Context, timeField, metaField string, granularity string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+	defer cancel()
+	timeSeries := primitive.D{{Key: "timeField", Value: timeField}}
+	if metaField != "" {
+		timeSeries = append(timeSeries, primitive.E{Key: "metaField", Value: metaField})
+	}
+	if granularity != "" {
+		timeSeries = append(timeSeries, primitive.E{Key: "granularity", Value: granularity})
+	}
+	command := primitive.D{
+		{Key: "create", Value: b.dbCollection},
+		{Key: "timeseries", Value: timeSeries},
+	}
+	return b.client.Database(b.dbName).RunCommand(ctx, command).Err()
+}
@@ -0,0 +1,182 @@
+package bom
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Update builds an update document operator by operator, pass Build() to UpdateRaw
+type Update struct {
+	operators primitive.D
+}
+
+func NewUpdate() *Update {
+	return &Update{}
+}
+
+func (u *Update) add(operator string, key string, value interface{}) *Update {
+	for i, op := range u.operators {
+		if op.Key == operator {
+			fields := op.Value.(primitive.D)
+			u.operators[i].Value = append(fields, primitive.E{Key: key, Value: value})
+			return u
+		}
+	}
+	u.operators = append(u.operators, primitive.E{Key: operator, Value: primitive.D{{Key: key, Value: value}}})
+	return u
+}
+
+func (u *Update) Set(field string, value interface{}) *Update {
+	return u.add("$set", field, value)
+}
+
+func (u *Update) Inc(field string, n interface{}) *Update {
+	return u.add("$inc", field, n)
+}
+
+func (u *Update) Push(field string, value interface{}) *Update {
+	return u.add("$push", field, value)
+}
+
+// SetNested sets a single leaf by its dot-path ("address.city"), so sibling fields of the subdocument are kept
+func (u *Update) SetNested(path string, value interface{}) *Update {
+	return u.add("$set", path, value)
+}
+
+// SetDeep flattens a nested map into dot-paths and sets every leaf,
+// unlike $set of the whole map it never replaces a subdocument
+func (u *Update) SetDeep(obj map[string]interface{}) *Update {
+	flattenPaths("", obj, func(path string, value interface{}) {
+		u.SetNested(path, value)
+	})
+	return u
+}
+
+// PushCapped pushes value (a slice pushes each element) into the array field, sorts the array by sortBy when given
+// and keeps only its last keepLast elements, all in one atomic $push with $each/$sort/$slice.
+// A keepLast of 0 or less leaves the array uncapped.
+func (u *Update) PushCapped(field string, value interface{}, keepLast int, sortBy bson.D) *Update {
+	push := primitive.D{{Key: "$each", Value: toSlice(value)}}
+	if len(sortBy) > 0 {
+		push = append(push, primitive.E{Key: "$sort", Value: sortBy})
+	}
+	if keepLast > 0 {
+		push = append(push, primitive.E{Key: "$slice", Value: -keepLast})
+	}
+	return u.add("$push", field, push)
+}
+
+func (u *Update) Build() primitive.D {
+	return u.operators
+}
+
+// Set, Inc and Push on the builder accumulate into one update document which Save applies in a single UpdateRaw
+func (b *Bom) Set(field string, value interface{}) *Bom {
+	b.getPendingUpdate().Set(field, value)
+	return b
+}
+
+func (b *Bom) Inc(field string, n interface{}) *Bom {
+	b.getPendingUpdate().Inc(field, n)
+	return b
+}
+
+func (b *Bom) Push(field string, value interface{}) *Bom {
+	b.getPendingUpdate().Push(field, value)
+	return b
+}
+
+// Save applies the accumulated Set, Inc and Push atomically to the first matching document
+// and clears them once the update went through
+func (b *Bom) Save() (*mongo.UpdateResult, error) {
+	if b.pendingUpdate == nil {
+		return nil, errors.New("nothing to save, use Set, Inc or Push first")
+	}
+	res, err := b.UpdateRaw(b.pendingUpdate.Build())
+	if err != nil {
+		return res, err
+	}
+	b.pendingUpdate = nil
+	return res, nil
+}
+
+func (b *Bom) getPendingUpdate() *Update {
+	if b.pendingUpdate == nil {
+		b.pendingUpdate = NewUpdate()
+	}
+	return b.pendingUpdate
+}
+
+// ErrNoUpdateOperators is returned by UpdateRaw and UpdateMany for an update document with plain fields,
+// use ReplaceOne to replace a whole document
+var ErrNoUpdateOperators = errors.New("update document must contain update operators like $set")
+
+// validateUpdate checks that every top-level key of the update document is an operator,
+// pipeline updates (a slice of stages) are passed through
+func validateUpdate(update interface{}) error {
+	if update == nil {
+		return ErrNoUpdateOperators
+	}
+	if kind := reflect.ValueOf(update).Kind(); kind == reflect.Slice && !isDocumentSlice(update) {
+		return nil
+	}
+	raw, err := bson.Marshal(update)
+	if err != nil {
+		return err
+	}
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return err
+	}
+	if len(elements) == 0 {
+		return ErrNoUpdateOperators
+	}
+	for _, element := range elements {
+		if !strings.HasPrefix(element.Key(), "$") {
+			return fmt.Errorf("%w, got field %q", ErrNoUpdateOperators, element.Key())
+		}
+	}
+	return nil
+}
+
+func isDocumentSlice(value interface{}) bool {
+	switch value.(type) {
+	case primitive.D, bson.Raw:
+		return true
+	}
+	return false
+}
+
+func flattenPaths(prefix string, obj map[string]interface{}, fn func(path string, value interface{})) {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		switch v := obj[key].(type) {
+		case map[string]interface{}:
+			if len(v) > 0 {
+				flattenPaths(path, v, fn)
+				continue
+			}
+		case primitive.M:
+			if len(v) > 0 {
+				flattenPaths(path, v, fn)
+				continue
+			}
+		}
+		fn(path, obj[key])
+	}
+}
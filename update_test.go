@@ -0,0 +1,29 @@
+package bom
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPushCapped(t *testing.T) {
+	got := NewUpdate().PushCapped("events", []interface{}{"a", "b"}, 10, nil).Build()
+	want := primitive.D{{Key: "$push", Value: primitive.D{{Key: "events", Value: primitive.D{
+		{Key: "$each", Value: []interface{}{"a", "b"}},
+		{Key: "$slice", Value: -10},
+	}}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PushCapped = %v, want %v", got, want)
+	}
+
+	got = NewUpdate().PushCapped("events", "a", 0, bson.D{{Key: "at", Value: 1}}).Build()
+	want = primitive.D{{Key: "$push", Value: primitive.D{{Key: "events", Value: primitive.D{
+		{Key: "$each", Value: []interface{}{"a"}},
+		{Key: "$sort", Value: bson.D{{Key: "at", Value: 1}}},
+	}}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PushCapped with keepLast 0 = %v, want %v", got, want)
+	}
+}
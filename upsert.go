@@ -0,0 +1,83 @@
+package bom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxUpsertRetries bounds how many times an upsert that lost a race on a unique index is retried
+const MaxUpsertRetries = 3
+
+var duplicateKeyCodes = map[int]bool{11000: true, 11001: true, 12582: true}
+
+func isDuplicateKeyError(err error) bool {
+	var writeException mongo.WriteException
+	if errors.As(err, &writeException) {
+		for _, we := range writeException.WriteErrors {
+			if duplicateKeyCodes[we.Code] {
+				return true
+			}
+		}
+	}
+	var bulkWriteException mongo.BulkWriteException
+	if errors.As(err, &bulkWriteException) {
+		for _, we := range bulkWriteException.WriteErrors {
+			if duplicateKeyCodes[we.Code] {
+				return true
+			}
+		}
+	}
+	var commandError mongo.CommandError
+	if errors.As(err, &commandError) {
+		return duplicateKeyCodes[int(commandError.Code)]
+	}
+	return false
+}
+
+// retryUpsert runs upsert again when two concurrent upserts both missed and one of them hit the unique index,
+// the retry then matches the document the other one inserted
+func retryUpsert(upsert func() error) error {
+	var err error
+	for attempt := 0; attempt < MaxUpsertRetries; attempt++ {
+		if err = upsert(); !isDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("upsert still conflicts after %d attempts: %w", MaxUpsertRetries, err)
+}
+
+// FindOrCreate decodes the document matching the conditions into result, inserting document first when
+// nothing matches. created tells whether the insert happened. Races on a unique index are retried.
+func (b *Bom) FindOrCreate(document interface{}, result interface{}) (created bool, err error) {
+	return b.FindOrCreateWithContext(context.Background(), document, result)
+}
+
+// FindOrCreateWithContext is FindOrCreate with every attempt bounded by ctx as well as the write timeout
+func (b *Bom) FindOrCreateWithContext(ctx context.Context, document interface{}, result interface{}) (created bool, err error) {
+	if err := b.Err(); err != nil {
+		return false, err
+	}
+	update := primitive.D{{Key: "$setOnInsert", Value: document}}
+	opts := append(b.getFindOneAndUpdateOptions(), options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before))
+	err = retryUpsert(func() error {
+		ctx, cancel := context.WithTimeout(ctx, b.getWriteTimeout())
+		defer cancel()
+		// $setOnInsert leaves an existing document untouched, so the "before" image is the current one
+		err := b.Mongo().FindOneAndUpdate(ctx, b.getCondition(), update, opts...).Decode(result)
+		if err == mongo.ErrNoDocuments {
+			created = true
+			findOptions := options.FindOne()
+			if projection, ok := b.buildProjection(); ok {
+				findOptions.SetProjection(projection)
+			}
+			return b.Mongo().FindOne(ctx, b.getCondition(), findOptions).Decode(result)
+		}
+		return err
+	})
+	return created, err
+}
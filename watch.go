@@ -0,0 +1,64 @@
+package bom
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WithStartAtOperationTime makes Watch resume from the given cluster time instead of "now"
+func (b *Bom) WithStartAtOperationTime(ts primitive.Timestamp) *Bom {
+	b.startAtOperationTime = &ts
+	return b
+}
+
+// ClusterTime returns the operation time the server reported for a session round-trip,
+// store it as a checkpoint and pass it to WithStartAtOperationTime to resume a Watch
+func (b *Bom) ClusterTime() (primitive.Timestamp, error) {
+	return b.ClusterTimeWithContext(context.Background())
+}
+
+// ClusterTimeWithContext is ClusterTime bounded by ctx as well as the read timeout
+func (b *Bom) ClusterTimeWithContext(ctx context.Context) (primitive.Timestamp, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	sess, err := b.client.StartSession()
+	if err != nil {
+		return primitive.Timestamp{}, err
+	}
+	defer sess.EndSession(ctx)
+	err = mongo.WithSession(ctx, sess, func(sc mongo.SessionContext) error {
+		return b.client.Database(b.dbName).RunCommand(sc, primitive.D{{Key: "ping", Value: 1}}).Err()
+	})
+	if err != nil {
+		return primitive.Timestamp{}, err
+	}
+	ts := sess.OperationTime()
+	if ts == nil {
+		return primitive.Timestamp{}, fmt.Errorf("operation time is not available, is the deployment a replica set?")
+	}
+	return *ts, nil
+}
+
+// Watch opens a change stream on the collection, the caller owns the stream and must Close it
+func (b *Bom) Watch(pipeline mongo.Pipeline) (*mongo.ChangeStream, error) {
+	return b.WatchWithContext(context.Background(), pipeline)
+}
+
+// WatchWithContext is Watch with opening the stream bounded by ctx as well as the read timeout,
+// pass a ctx to the stream's Next to bound the reads
+func (b *Bom) WatchWithContext(ctx context.Context, pipeline mongo.Pipeline) (*mongo.ChangeStream, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.getReadTimeout())
+	defer cancel()
+	changeStreamOptions := options.ChangeStream()
+	if b.startAtOperationTime != nil {
+		changeStreamOptions.SetStartAtOperationTime(b.startAtOperationTime)
+	}
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	return b.Mongo().Watch(ctx, pipeline, changeStreamOptions)
+}